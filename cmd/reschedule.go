@@ -1,23 +1,37 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/lentil1016/descheduler/pkg/descheduler"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
 	"github.com/spf13/cobra"
 )
 
 // doDescheduleCmd do the calculate and then deschedule
 func doDescheduleCmd(cmd *cobra.Command, args []string) {
+	if err := loadConfig(""); err != nil {
+		logger.Error(err, "Invalid config")
+		os.Exit(1)
+	}
 	d, err := descheduler.CreateDescheduler()
 	if err != nil {
-		fmt.Println(err)
+		logger.Error(err, "Failed to create descheduler")
 		return
 	}
 
+	if metricsAddr != "" {
+		go func() {
+			logger.Info("Serving metrics", "addr", metricsAddr)
+			if err := metrics.Serve(metricsAddr); err != nil {
+				logger.Error(err, "Metrics server stopped")
+			}
+		}()
+	}
+
 	stopCh := make(chan struct{})
 	defer close(stopCh)
 	go d.Run(stopCh)
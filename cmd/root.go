@@ -15,16 +15,21 @@
 package cmd
 
 import (
-	"fmt"
+	goflag "flag"
 	"os"
 
 	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/descheduler"
+	"github.com/lentil1016/descheduler/pkg/logger"
 	"github.com/spf13/cobra"
+	"k8s.io/klog"
 )
 
 var configFile string
 var kubeConfigFile string
 var dryRun bool
+var metricsAddr string
+var logFormat string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -39,7 +44,7 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		logger.Error(err, "Command failed")
 		os.Exit(1)
 	}
 }
@@ -49,9 +54,31 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "deschedule policy config file (default is $HOME/.kubewatch.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&kubeConfigFile, "kubeconfig", "k", "", "kubeConfig file (default is $HOME/.kube/config)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "use dry run mod")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "address to serve prometheus metrics on, e.g. :8080 (default is disabled)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logger.TextFormat, "log format, either text or json")
+
+	// Only the verbosity of klog is exposed, logs always go to stderr.
+	klogFlags := goflag.NewFlagSet("klog", goflag.ExitOnError)
+	klog.InitFlags(klogFlags)
+	rootCmd.PersistentFlags().AddGoFlag(klogFlags.Lookup("v"))
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig sets up logging before any command runs.
 func initConfig() {
-	config.InitConfig(configFile, kubeConfigFile, dryRun)
+	if err := logger.SetFormat(logFormat); err != nil {
+		logger.Error(err, "Invalid --log-format")
+		os.Exit(1)
+	}
+}
+
+// loadConfig reads in the config file, then checks that descheduler can work
+// with it. The config file given by path overrides the --config flag.
+func loadConfig(path string) error {
+	if path == "" {
+		path = configFile
+	}
+	if err := config.InitConfig(path, kubeConfigFile, dryRun); err != nil {
+		return err
+	}
+	return descheduler.ValidateConfig(config.GetConfig())
 }
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate-config [FILE]",
+	Short: "Validate the config file and exit.",
+	Long: "This command loads the config file like descheduler does at start, reports every problem found in it, " +
+		"and exits non-zero if there is any. FILE overrides the --config flag.",
+	Args: cobra.MaximumNArgs(1),
+	Run:  doValidateConfigCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(validateConfigCmd)
+}
+
+func doValidateConfigCmd(cmd *cobra.Command, args []string) {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if err := loadConfig(path); err != nil {
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			for _, e := range agg.Errors() {
+				fmt.Fprintln(os.Stderr, e)
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("Config is valid")
+}
@@ -0,0 +1,64 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func (in *DeschedulerPolicy) DeepCopyInto(out *DeschedulerPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+func (in *DeschedulerPolicy) DeepCopy() *DeschedulerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeschedulerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *DeschedulerPolicy) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func (in *DeschedulerPolicyStatus) DeepCopyInto(out *DeschedulerPolicyStatus) {
+	*out = *in
+	if in.LastRunTime != nil {
+		out.LastRunTime = in.LastRunTime.DeepCopy()
+	}
+	if in.LastEvictedPods != nil {
+		out.LastEvictedPods = append([]string(nil), in.LastEvictedPods...)
+	}
+	if in.LastFailedRecoveries != nil {
+		out.LastFailedRecoveries = append([]string(nil), in.LastFailedRecoveries...)
+	}
+}
+
+func (in *DeschedulerPolicyList) DeepCopyInto(out *DeschedulerPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]DeschedulerPolicy, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *DeschedulerPolicyList) DeepCopy() *DeschedulerPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(DeschedulerPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *DeschedulerPolicyList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// States of a DeschedulerPolicy.
+const (
+	// StateIdle is waiting for the next deschedule trigger.
+	StateIdle = "idle"
+	// StateDescheduling is picking and evicting pods.
+	StateDescheduling = "descheduling"
+	// StateRecovering is waiting for the evicted workloads to recover.
+	StateRecovering = "recovering"
+	// StateInvalid is rejecting the spec, the previous spec accepted keeps working if any.
+	StateInvalid = "invalid"
+)
+
+// DeschedulerPolicy is a cluster scoped set of triggers and rules that
+// descheduler deschedules by, alongside the other policies.
+type DeschedulerPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec has the layout of the spec of the config file, the fields missing in it
+	// are taken from the config file. Only triggers and rules can be set, except
+	// spec.triggers.mode, spec.triggers.time and spec.rules.evictJobPods.
+	Spec   runtime.RawExtension    `json:"spec"`
+	Status DeschedulerPolicyStatus `json:"status,omitempty"`
+}
+
+type DeschedulerPolicyStatus struct {
+	State                string       `json:"state,omitempty"`                // One of idle, descheduling, recovering and invalid.
+	Message              string       `json:"message,omitempty"`              // Why the spec is invalid.
+	ObservedGeneration   int64        `json:"observedGeneration,omitempty"`   // Generation of the spec the status is about.
+	LastRunTime          *metav1.Time `json:"lastRunTime,omitempty"`          // Time the latest deschedule term started.
+	LastEvictedPods      []string     `json:"lastEvictedPods,omitempty"`      // Keys of the pods evicted in the latest deschedule term.
+	LastFailedRecoveries []string     `json:"lastFailedRecoveries,omitempty"` // Keys of the workloads that failed to recover in the latest abandoned recovery.
+}
+
+// DeschedulerPolicyList is a list of DeschedulerPolicy.
+type DeschedulerPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []DeschedulerPolicy `json:"items"`
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/apis/descheduler/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+)
+
+// DeschedulerPolicyInterface reads DeschedulerPolicies and writes their status.
+type DeschedulerPolicyInterface interface {
+	Get(name string, opts metav1.GetOptions) (*v1alpha1.DeschedulerPolicy, error)
+	List(opts metav1.ListOptions) (*v1alpha1.DeschedulerPolicyList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	UpdateStatus(policy *v1alpha1.DeschedulerPolicy) (*v1alpha1.DeschedulerPolicy, error)
+}
+
+var (
+	scheme         = runtime.NewScheme()
+	codecs         = serializer.NewCodecFactory(scheme)
+	parameterCodec = runtime.NewParameterCodec(scheme)
+)
+
+func init() {
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+}
+
+type deschedulerPolicies struct {
+	client rest.Interface
+}
+
+// NewForConfig creates a DeschedulerPolicy client, the DeschedulerPolicy
+// CustomResourceDefinition in manifest.yaml must be created in the cluster.
+func NewForConfig(c *rest.Config) (DeschedulerPolicyInterface, error) {
+	config := *c
+	config.GroupVersion = &v1alpha1.SchemeGroupVersion
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: codecs}
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &deschedulerPolicies{client: client}, nil
+}
+
+func (c *deschedulerPolicies) Get(name string, opts metav1.GetOptions) (*v1alpha1.DeschedulerPolicy, error) {
+	result := &v1alpha1.DeschedulerPolicy{}
+	err := c.client.Get().
+		Resource(v1alpha1.Resource).
+		Name(name).
+		VersionedParams(&opts, parameterCodec).
+		Do().
+		Into(result)
+	return result, err
+}
+
+func (c *deschedulerPolicies) List(opts metav1.ListOptions) (*v1alpha1.DeschedulerPolicyList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result := &v1alpha1.DeschedulerPolicyList{}
+	err := c.client.Get().
+		Resource(v1alpha1.Resource).
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return result, err
+}
+
+func (c *deschedulerPolicies) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource(v1alpha1.Resource).
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+func (c *deschedulerPolicies) UpdateStatus(policy *v1alpha1.DeschedulerPolicy) (*v1alpha1.DeschedulerPolicy, error) {
+	result := &v1alpha1.DeschedulerPolicy{}
+	err := c.client.Put().
+		Resource(v1alpha1.Resource).
+		Name(policy.Name).
+		SubResource("status").
+		Body(policy).
+		Do().
+		Into(result)
+	return result, err
+}
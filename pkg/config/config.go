@@ -1,171 +1,467 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/lentil1016/descheduler/pkg/logger"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 const currentApiVersion = "descheduler.lentil1016.cn/v1alpha1"
 
+// config is the whole config file.
 type config struct {
-	apiVersion string     `yaml: apiVersion`
-	spec       ConfigSpec `yaml: spec`
+	APIVersion string     `yaml:"apiVersion"`
+	Spec       ConfigSpec `yaml:"spec"`
 }
 
 type ConfigSpec struct {
-	KubeConfigFile string         `yaml: kubeconfig`
-	DryRun         bool           `yaml: dryRun`
-	Triggers       ConfigTriggers `yaml: triggers`
-	Rules          ConfigRules    `yaml: rules`
+	KubeConfigFile string               `yaml:"kubeconfig"`
+	DryRun         bool                 `yaml:"dryRun"`
+	Triggers       ConfigTriggers       `yaml:"triggers"`
+	Rules          ConfigRules          `yaml:"rules"`
+	LeaderElection ConfigLeaderElection `yaml:"leaderElection"`
+	WatchPolicies  bool                 `yaml:"watchPolicies"` // Deschedule by the DeschedulerPolicy resources instead, the triggers and rules here are the defaults of the policies.
 }
 
 type ConfigTriggers struct {
-	AllReplicasOnOneNode bool                     `yaml: allReplicasOnOneNode` // If all pods(more than one) of a replicaSet run on one single node, evict one of them.
-	MinSparedPercentage  ConfigResourcePercentage `yaml: minSparedPercentage`
-	MaxSparedPercentage  ConfigResourcePercentage `yaml: maxSparedPercentage`
-	Mode                 string                   `yaml: mode`
-	Time                 ConfigTime               `yaml: time`
+	AllReplicasOnOneNode bool                     `yaml:"allReplicasOnOneNode"` // If all pods(more than one) of a replicaSet run on one single node, evict one of them.
+	MinSparedPercentage  ConfigResourcePercentage `yaml:"minSparedPercentage"`  // Nodes with less spared than it of any resource are busy, 0 turns it off for the resource.
+	MaxSparedPercentage  ConfigResourcePercentage `yaml:"maxSparedPercentage"`  // Nodes with more spared than it of some resource are spared, 100 turns it off for the resource.
+	ResourceWeights      ConfigResourceWeight     `yaml:"resourceWeights"`      // Weights of the resources in node scores, a resource missing weighs 1, weights must be positive.
+	Scorer               ConfigScorer             `yaml:"scorer"`
+	Mode                 string                   `yaml:"mode"`
+	Time                 ConfigTime               `yaml:"time"`
+	MetricsWeight        ConfigMetricsWeight      `yaml:"metricsWeight"` // Share of the live usage read from metrics.k8s.io in the node usage, the rest is calculated from pod requests. Only node metrics are read, pods are still picked and placed by their requests.
 }
 
-type ConfigResourcePercentage struct {
-	CPU    float64 `yaml: cpu`
-	Memory float64 `yaml: memory`
-	Pod    float64 `yaml: pod`
+// ConfigResourcePercentage are percentages by resource names. Besides cpu, memory and pod
+// for the number of pods, any resource reported by nodes can be set, like
+// ephemeral-storage, hugepages-2Mi or nvidia.com/gpu. The resources not set keep
+// their defaults, the defaults of cpu, memory and pod are turned off by 0 in
+// minSparedPercentage and 100 in maxSparedPercentage rather than left out.
+type ConfigResourcePercentage map[string]float64
+
+// ConfigResourceWeight are weights by the resource names of ConfigResourcePercentage.
+type ConfigResourceWeight map[string]float64
+
+// ConfigScorer selects the function scoring the nodes by their resource usages.
+type ConfigScorer struct {
+	Name   string             `yaml:"name"`   // Either squaredSum, max, weightedLinear, or a scorer registered by the program embedding descheduler.
+	Params map[string]float64 `yaml:"params"` // Parameters of the scorer, the built-in ones take relative: 1 to score the resources by how far they are over their lines.
+}
+
+// ResourcePod is the resource name of the number of pods in ConfigResourcePercentage.
+const ResourcePod = "pod"
+
+// Resources returns the resource names in any of the percentages, sorted.
+func Resources(percentages ...ConfigResourcePercentage) []string {
+	names := []string{}
+	for _, p := range percentages {
+		for name := range p {
+			if !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type ConfigMetricsWeight struct {
+	CPU    float64 `yaml:"cpu"`    // 0 uses requests only, 1 uses metrics only.
+	Memory float64 `yaml:"memory"` // 0 uses requests only, 1 uses metrics only.
 }
 
 type ConfigTime struct {
-	From time.Time `yaml: from`
-	For  string    `yaml: for`
+	From       ClockTime         `yaml:"from"`       // Start of the daily window, used when windows is empty.
+	For        string            `yaml:"for"`        // Duration of the daily window, used when windows is empty.
+	Windows    []ConfigWindow    `yaml:"windows"`    // Windows in which descheduling is allowed.
+	Exclusions []ConfigExclusion `yaml:"exclusions"` // Ranges of time in which descheduling is forbidden, even inside windows.
+}
+
+type ConfigWindow struct {
+	Schedule string `yaml:"schedule"` // Cron expression of the times the window starts, like "0 22 * * MON-FRI".
+	For      string `yaml:"for"`      // Duration of the window.
+	Timezone string `yaml:"timezone"` // IANA timezone of the schedule, like "Asia/Shanghai", empty uses the local timezone.
+}
+
+type ConfigExclusion struct {
+	From     string `yaml:"from"`     // RFC3339 time or date the exclusion starts at.
+	To       string `yaml:"to"`       // RFC3339 time or date the exclusion ends at, a date includes the whole day.
+	Timezone string `yaml:"timezone"` // IANA timezone of the dates, empty uses the local timezone.
+}
+
+// ClockTime is a time of the day, like "10:00PM" or "22:00".
+type ClockTime struct {
+	time.Time
+}
+
+var clockTimeLayouts = []string{time.Kitchen, "3:04 PM", "15:04", time.RFC3339}
+
+func (c *ClockTime) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	for _, layout := range clockTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			c.Time = t
+			return nil
+		}
+	}
+	return fmt.Errorf("Can't parse %q as a time of the day, expecting a time like 10:00PM or 22:00", value)
 }
 
 type ConfigRules struct {
-	HardEviction     bool     `yaml: hardEviction`     // Evicting a pod when it's the only replica of the replicaSet it belongs.
-	AffectNamespaces []string `yaml: affectNamespaces` // Namespaces that descheduler will affect to, an empty slice indicates all namespaces
-	NodeSelector     string   `yaml: nodeSelector`     // Selectors of the nodes that descheduler will affect to, nil indicates all nodes.
-	MaxEvictSize     int      `yaml: maxEvictSize`     // Number of the Pod in one deschedule term will be evicted at most.
+	HardEviction        bool                 `yaml:"hardEviction"`        // Evicting a pod when it's the only replica of the workload it belongs.
+	AffectNamespaces    []string             `yaml:"affectNamespaces"`    // Namespaces that descheduler will affect to, an empty slice indicates all namespaces
+	ExcludeNamespaces   []string             `yaml:"excludeNamespaces"`   // Namespaces that descheduler won't affect to, even if they are in affectNamespaces.
+	NodeSelector        string               `yaml:"nodeSelector"`        // Selectors of the nodes that descheduler will affect to, nil indicates all nodes.
+	MaxEvictSize        int                  `yaml:"maxEvictSize"`        // Number of the Pod in one deschedule term will be evicted at most.
+	EvictBudget         ConfigEvictBudget    `yaml:"evictBudget"`         // Finer limits of the evictions besides maxEvictSize.
+	SelectionMode       string               `yaml:"selectionMode"`       // Mode of picking pods from busy nodes, either byNode or fair.
+	EvictOnlyNeeded     bool                 `yaml:"evictOnlyNeeded"`     // Stop picking pods from a busy node once it's projected to be back under its usage line.
+	RecoverTimeout      string               `yaml:"recoverTimeout"`      // Duration to wait for evicted workloads to recover before abandoning the recovery, 0 waits forever.
+	EvictJobPods        bool                 `yaml:"evictJobPods"`        // Evicting the pods of Jobs, which lose their progress after being evicted.
+	AnnotateEvictedPods bool                 `yaml:"annotateEvictedPods"` // Stamping the eviction decision as an annotation on pods right before evicting them.
+	TaintKeys           []string             `yaml:"taintKeys"`           // Keys of the NoSchedule taints that pods not tolerating them are evicted for, an empty slice indicates all keys.
+	IgnoredTaintKeys    []string             `yaml:"ignoredTaintKeys"`    // Keys of the NoSchedule taints that pods are never evicted for, even if they are in taintKeys.
+	MaxPriority         int32                `yaml:"maxPriority"`         // Priority of the pods above which they are never evicted.
+	ExcludeNodes        ConfigNodeExclusions `yaml:"excludeNodes"`        // Nodes that descheduler won't evict pods from nor take as targets, besides the not ready ones.
 }
 
-func setDefaults() {
-	var defaultFromTime = time.Now()
+type ConfigEvictBudget struct {
+	PerNode      int `yaml:"perNode"`      // Number of the Pod in one deschedule term will be evicted from each node at most, 0 indicates no limit.
+	PerNamespace int `yaml:"perNamespace"` // Number of the Pod in one deschedule term will be evicted from each namespace at most, 0 indicates no limit.
+	PerWorkload  int `yaml:"perWorkload"`  // Number of the Pod in one deschedule term will be evicted from each workload at most, 0 indicates no limit.
+	PerHour      int `yaml:"perHour"`      // Number of the Pod will be evicted in any past hour at most, 0 indicates no limit.
+}
 
-	var defaultConf = ConfigSpec{
-		DryRun: false,
+type ConfigNodeExclusions struct {
+	LabelSelector string   `yaml:"labelSelector"` // Selectors of the nodes excluded, an empty string excludes none.
+	TaintKeys     []string `yaml:"taintKeys"`     // Keys of the taints of any effect that exclude the nodes having them.
+	MinAge        string   `yaml:"minAge"`        // Duration a node must have been created for before descheduler affects it, 0 affects new nodes at once.
+	Cordoned      bool     `yaml:"cordoned"`      // Excluding cordoned nodes as sources too, they are never taken as targets anyway.
+}
+
+type ConfigLeaderElection struct {
+	Enabled       bool   `yaml:"enabled"`       // Run leader election so that only one of the replicas deschedules at a time.
+	ResourceLock  string `yaml:"resourceLock"`  // Type of the resource used as the lock, either leases or configmaps.
+	Namespace     string `yaml:"namespace"`     // Namespace of the lock resource.
+	Name          string `yaml:"name"`          // Name of the lock resource.
+	LeaseDuration string `yaml:"leaseDuration"` // Duration that standby replicas wait before taking over the leadership.
+	RenewDeadline string `yaml:"renewDeadline"` // Duration that the leader retries refreshing the leadership before giving up.
+	RetryPeriod   string `yaml:"retryPeriod"`   // Duration between tries of acquiring or renewing the leadership.
+}
+
+// DefaultConfig returns the config used for the fields missing in the config file.
+func DefaultConfig() ConfigSpec {
+	return ConfigSpec{
+		KubeConfigFile: defaultKubeConfigFile(),
+		DryRun:         false,
 		Triggers: ConfigTriggers{
 			AllReplicasOnOneNode: true,
 			MinSparedPercentage: ConfigResourcePercentage{
-				CPU:    30,
-				Memory: 30,
-				Pod:    30,
+				"cpu":       30,
+				"memory":    30,
+				ResourcePod: 30,
 			},
 			MaxSparedPercentage: ConfigResourcePercentage{
-				CPU:    70,
-				Memory: 70,
-				Pod:    70,
+				"cpu":       70,
+				"memory":    70,
+				ResourcePod: 70,
 			},
-			Mode: "event",
+			ResourceWeights: ConfigResourceWeight{},
+			Scorer:          ConfigScorer{Name: "squaredSum", Params: map[string]float64{}},
+			Mode:            "event",
 			Time: ConfigTime{
-				From: defaultFromTime,
+				From: ClockTime{time.Now()},
 				For:  "1h",
 			},
+			MetricsWeight: ConfigMetricsWeight{
+				CPU:    0,
+				Memory: 0,
+			},
 		},
 		Rules: ConfigRules{
-			HardEviction:     false,
-			AffectNamespaces: []string{},
-			NodeSelector:     "",
-			MaxEvictSize:     3,
+			HardEviction:      false,
+			AffectNamespaces:  []string{},
+			ExcludeNamespaces: []string{},
+			NodeSelector:      "",
+			MaxEvictSize:      3,
+			SelectionMode:     "byNode",
+			EvictOnlyNeeded:   false,
+			EvictBudget: ConfigEvictBudget{
+				PerNode:      0,
+				PerNamespace: 0,
+				PerWorkload:  0,
+				PerHour:      0,
+			},
+			RecoverTimeout:      "10m",
+			EvictJobPods:        false,
+			AnnotateEvictedPods: false,
+			TaintKeys:           []string{},
+			// Cordoned nodes are drained by their admins, not by descheduler.
+			IgnoredTaintKeys: []string{"node.kubernetes.io/unschedulable"},
+			// The highest priority of user defined PriorityClasses, leaving
+			// out the system-cluster-critical and system-node-critical pods.
+			MaxPriority: 1000000000,
+			ExcludeNodes: ConfigNodeExclusions{
+				LabelSelector: "",
+				TaintKeys:     []string{},
+				MinAge:        "0",
+				Cordoned:      false,
+			},
+		},
+		LeaderElection: ConfigLeaderElection{
+			Enabled:       false,
+			ResourceLock:  "leases",
+			Namespace:     "kube-system",
+			Name:          "descheduler",
+			LeaseDuration: "15s",
+			RenewDeadline: "10s",
+			RetryPeriod:   "2s",
 		},
 	}
+}
 
-	viper.SetDefault("spec.dryRun", defaultConf.DryRun)
-	viper.SetDefault("spec.triggers.preventAllReplicasOnOneNode", defaultConf.Triggers.AllReplicasOnOneNode)
-	viper.SetDefault("spec.triggers.minSparedPercentage.cpu", defaultConf.Triggers.MinSparedPercentage.CPU)
-	viper.SetDefault("spec.triggers.minSparedPercentage.memory", defaultConf.Triggers.MinSparedPercentage.Memory)
-	viper.SetDefault("spec.triggers.minSparedPercentage.pod", defaultConf.Triggers.MinSparedPercentage.Pod)
-	viper.SetDefault("spec.triggers.maxSparedPercentage.cpu", defaultConf.Triggers.MaxSparedPercentage.CPU)
-	viper.SetDefault("spec.triggers.maxSparedPercentage.memory", defaultConf.Triggers.MaxSparedPercentage.Memory)
-	viper.SetDefault("spec.triggers.maxSparedPercentage.pod", defaultConf.Triggers.MaxSparedPercentage.Pod)
-	viper.SetDefault("spec.triggers.mode", defaultConf.Triggers.Mode)
-	viper.SetDefault("spec.triggers.time.from", defaultConf.Triggers.Time.From)
-	viper.SetDefault("spec.triggers.time.for", defaultConf.Triggers.Time.For)
-	viper.SetDefault("spec.rules.hardEviction", defaultConf.Rules.HardEviction)
-	viper.SetDefault("spec.rules.affectNamespaces", defaultConf.Rules.AffectNamespaces)
-	viper.SetDefault("spec.rules.nodeSelector", defaultConf.Rules.NodeSelector)
-	viper.SetDefault("spec.rules.maxEvictSize", defaultConf.Rules.MaxEvictSize)
-}
-
-func InitConfig(configFile string, kubeConfigFile string, dryRun bool) {
-	// Find home directory.
+func defaultKubeConfigFile() string {
 	home, err := homedir.Dir()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return ""
+	}
+	return path.Join(home, ".kube/config")
+}
+
+// Names of the config file searched in the home directory when no config file is given.
+var defaultConfigFiles = []string{".descheduler.yaml", ".descheduler.yml", ".descheduler.json"}
+
+var (
+	// conf is the config loaded by InitConfig.
+	conf ConfigSpec
+	// configFileUsed is the path of the config file loaded, empty when no config file is found.
+	configFileUsed string
+	// kubeConfigFileOverride and dryRunOverride are given by the cmdline, they
+	// override the config file contents every time it is loaded.
+	kubeConfigFileOverride string
+	dryRunOverride         bool
+)
+
+// InitConfig loads the config file, the default config is used when no config
+// file is given and none is found in the home directory.
+func InitConfig(configFile string, kubeConfigFile string, dryRun bool) error {
+	kubeConfigFileOverride = kubeConfigFile
+	dryRunOverride = dryRun
+
+	if configFile == "" {
+		configFile = findConfigFile()
+	}
+	if configFile == "" {
+		logger.Info("No config file found, using the default config")
+		conf = DefaultConfig()
+		applyOverrides(&conf)
+		return conf.Validate()
 	}
 
-	// set config file path
-	if configFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(configFile)
-	} else {
-		// Search config in home directory with name ".descheduler" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".descheduler")
+	logger.Info("Using config file", "path", configFile)
+	loaded, err := LoadFile(configFile)
+	if err != nil {
+		return err
 	}
-	viper.SetDefault("spec.kubeconfig", path.Join(home, ".kube/config"))
-	setDefaults()
-	viper.AutomaticEnv() // read in environment variables that match
+	conf = loaded
+	configFileUsed = configFile
+	return nil
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+// findConfigFile returns the first config file found in the home directory.
+func findConfigFile() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		logger.Error(err, "Failed to find home directory")
+		return ""
 	}
-	// If config file have a wrong apiVersion, reset config to default
-	if viper.GetString("apiVersion") != currentApiVersion {
-		fmt.Printf("Error apiVersion %v in config file %v, expecting for %v. will use the default config\n", viper.GetString("apiVersion"), viper.ConfigFileUsed(), currentApiVersion)
-		viper.Reset()
+	for _, name := range defaultConfigFiles {
+		file := path.Join(home, name)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
 	}
+	return ""
+}
+
+// LoadFile loads the config file like InitConfig does, without keeping it.
+func LoadFile(configFile string) (ConfigSpec, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return ConfigSpec{}, fmt.Errorf("Failed to read config file %v: %v", configFile, err)
+	}
+	loaded, err := Load(data)
+	if err != nil {
+		return ConfigSpec{}, err
+	}
+	applyOverrides(&loaded)
+	return loaded, nil
+}
+
+func applyOverrides(c *ConfigSpec) {
+	if kubeConfigFileOverride != "" {
+		c.KubeConfigFile = kubeConfigFileOverride
+	}
+	if dryRunOverride {
+		c.DryRun = true
+	}
+}
+
+// Load decodes a config file over the default config. Unknown or duplicated
+// keys, values of the wrong type, a wrong apiVersion and values failing
+// Validate are all reported as errors.
+func Load(data []byte) (ConfigSpec, error) {
+	defaults := DefaultConfig()
+	file := config{Spec: defaults}
+	file.Spec.clearMaps()
+	if err := unmarshalStrict(data, &file); err != nil {
+		return ConfigSpec{}, err
+	}
+	file.Spec.mergeMaps(defaults)
+	if file.APIVersion != currentApiVersion {
+		return ConfigSpec{}, fmt.Errorf("Can't recognize apiVersion with value %q, expecting %q", file.APIVersion, currentApiVersion)
+	}
+	if err := file.Spec.Validate(); err != nil {
+		return ConfigSpec{}, err
+	}
+	return file.Spec, nil
+}
+
+// unmarshalStrict reports every key that can't be decoded as an error of its own.
+func unmarshalStrict(data []byte, out interface{}) error {
+	err := yaml.UnmarshalStrict(data, out)
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		var errs []error
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, errors.New(msg))
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+	return err
+}
+
+// LoadPolicy decodes the spec of a DeschedulerPolicy over the config file
+// strictly like Load does. A policy only sets the triggers and the rules that
+// can differ between policies, the others are taken from the config file.
+func LoadPolicy(base ConfigSpec, spec []byte) (ConfigSpec, error) {
+	policy := base
+	// An empty spec would reset the whole config instead of keeping it.
+	if trimmed := strings.TrimSpace(string(spec)); trimmed == "" || trimmed == "null" {
+		return policy, nil
+	}
+	policy.clearMaps()
+	if err := unmarshalStrict(spec, &policy); err != nil {
+		return ConfigSpec{}, err
+	}
+	policy.mergeMaps(base)
+	var errs []error
+	for _, field := range []struct {
+		name         string
+		base, policy interface{}
+	}{
+		{"spec.kubeconfig", base.KubeConfigFile, policy.KubeConfigFile},
+		{"spec.dryRun", base.DryRun, policy.DryRun},
+		{"spec.triggers.mode", base.Triggers.Mode, policy.Triggers.Mode},
+		{"spec.triggers.time", base.Triggers.Time, policy.Triggers.Time},
+		{"spec.rules.evictJobPods", base.Rules.EvictJobPods, policy.Rules.EvictJobPods},
+		{"spec.leaderElection", base.LeaderElection, policy.LeaderElection},
+		{"spec.watchPolicies", base.WatchPolicies, policy.WatchPolicies},
+	} {
+		if !reflect.DeepEqual(field.base, field.policy) {
+			errs = append(errs, fmt.Errorf("%v can only be set in the config file", field.name))
+		}
+	}
+	if len(errs) > 0 {
+		return ConfigSpec{}, utilerrors.NewAggregate(errs)
+	}
+	if err := policy.Validate(); err != nil {
+		return ConfigSpec{}, err
+	}
+	return policy, nil
+}
+
+// clearMaps and mergeMaps decode the maps over the base ones. Strict decoding
+// refuses the keys already in a map, so the maps are decoded into empty ones
+// and then take the values of the base ones for the keys missing.
+func (c *ConfigSpec) clearMaps() {
+	c.Triggers.MinSparedPercentage = nil
+	c.Triggers.MaxSparedPercentage = nil
+	c.Triggers.ResourceWeights = nil
+	c.Triggers.Scorer.Params = nil
+}
 
-	// Cmdline param overrides config file contents
-	if kubeConfigFile != "" {
-		viper.Set("spec.kubeconfig", kubeConfigFile)
+func (c *ConfigSpec) mergeMaps(base ConfigSpec) {
+	c.Triggers.MinSparedPercentage = mergeMap(c.Triggers.MinSparedPercentage, base.Triggers.MinSparedPercentage)
+	c.Triggers.MaxSparedPercentage = mergeMap(c.Triggers.MaxSparedPercentage, base.Triggers.MaxSparedPercentage)
+	c.Triggers.ResourceWeights = mergeMap(c.Triggers.ResourceWeights, base.Triggers.ResourceWeights)
+	// the params of another scorer mean nothing to the one set
+	baseParams := base.Triggers.Scorer.Params
+	if c.Triggers.Scorer.Name != base.Triggers.Scorer.Name {
+		baseParams = nil
 	}
-	if dryRun == true {
-		viper.Set("spec.dryRun", dryRun)
+	c.Triggers.Scorer.Params = mergeMap(c.Triggers.Scorer.Params, baseParams)
+}
+
+func mergeMap(m, base map[string]float64) map[string]float64 {
+	merged := make(map[string]float64, len(base)+len(m))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range m {
+		merged[k] = v
 	}
+	return merged
 }
 
+// GetConfig returns the config loaded by InitConfig.
 func GetConfig() ConfigSpec {
-	// Create config with values in viper
-	return ConfigSpec{
-		KubeConfigFile: viper.GetString("spec.kubeconfig"),
-		DryRun:         viper.GetBool("spec.dryRun"),
-		Triggers: ConfigTriggers{
-			AllReplicasOnOneNode: viper.GetBool("spec.triggers.allReplicasOnOneNode"),
-			MinSparedPercentage: ConfigResourcePercentage{
-				CPU:    viper.GetFloat64("spec.triggers.minSparedPercentage.cpu"),
-				Memory: viper.GetFloat64("spec.triggers.minSparedPercentage.memory"),
-				Pod:    viper.GetFloat64("spec.triggers.minSparedPercentage.pod"),
-			},
-			MaxSparedPercentage: ConfigResourcePercentage{
-				CPU:    viper.GetFloat64("spec.triggers.maxSparedPercentage.cpu"),
-				Memory: viper.GetFloat64("spec.triggers.maxSparedPercentage.memory"),
-				Pod:    viper.GetFloat64("spec.triggers.maxSparedPercentage.pod"),
-			},
-			Mode: viper.GetString("spec.triggers.mode"),
-			Time: ConfigTime{
-				From: viper.GetTime("spec.triggers.time.from"),
-				For:  viper.GetString("spec.triggers.time.for"),
-			},
-		},
-		Rules: ConfigRules{
-			HardEviction:     viper.GetBool("spec.rules.hardEviction"),
-			AffectNamespaces: viper.GetStringSlice("spec.rules.affectNamespaces"),
-			NodeSelector:     viper.GetString("spec.rules.nodeSelector"),
-			MaxEvictSize:     viper.GetInt("spec.rules.maxEvictSize"),
-		},
+	return conf
+}
+
+// WatchConfig calls onChange with the config every time the config file changes.
+// Changes that can't be loaded are logged and never passed to onChange.
+func WatchConfig(onChange func(ConfigSpec)) {
+	if configFileUsed == "" {
+		return
 	}
+	// viper is only used to watch the file, it follows the symlinks
+	// swapped by kubelet when the ConfigMap mounted is updated.
+	viper.SetConfigFile(configFileUsed)
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		changed, err := LoadFile(configFileUsed)
+		if err != nil {
+			logger.Error(err, "Config reload rejected, keeping the previous config", "path", configFileUsed)
+			return
+		}
+		onChange(changed)
+	})
+	viper.WatchConfig()
+	logger.Info("Watching config file", "path", configFileUsed)
 }
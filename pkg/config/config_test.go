@@ -0,0 +1,226 @@
+package config
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestLoadExample(t *testing.T) {
+	data, err := ioutil.ReadFile("../../examples/descheduler.yaml")
+	require.NoError(t, err)
+	conf, err := Load(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "time", conf.Triggers.Mode)
+	assert.Equal(t, 22, conf.Triggers.Time.From.Hour())
+	assert.Len(t, conf.Triggers.Time.Windows, 2)
+	assert.Equal(t, 0.5, conf.Triggers.MetricsWeight.CPU)
+	assert.Equal(t, []string{"kube-system"}, conf.Rules.ExcludeNamespaces)
+	// Missing keys keep the default.
+	assert.True(t, conf.Triggers.AllReplicasOnOneNode)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	conf, err := Load([]byte(`
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        allReplicasOnOneNode: false
+        time:
+            from: "22:30"
+    rules:
+        maxEvictSize: 1
+`))
+	require.NoError(t, err)
+	defaults := DefaultConfig()
+	assert.False(t, conf.Triggers.AllReplicasOnOneNode)
+	assert.Equal(t, 1, conf.Rules.MaxEvictSize)
+	assert.Equal(t, 22, conf.Triggers.Time.From.Hour())
+	assert.Equal(t, 30, conf.Triggers.Time.From.Minute())
+	assert.Equal(t, defaults.Triggers.MinSparedPercentage, conf.Triggers.MinSparedPercentage)
+	assert.Equal(t, defaults.Rules.RecoverTimeout, conf.Rules.RecoverTimeout)
+	assert.Equal(t, defaults.LeaderElection, conf.LeaderElection)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errs   []string
+	}{
+		{
+			name:   "wrong apiVersion",
+			config: "apiVersion: descheduler/v1\n",
+			errs:   []string{`Can't recognize apiVersion with value "descheduler/v1", expecting "descheduler.lentil1016.cn/v1alpha1"`},
+		},
+		{
+			name: "unknown keys",
+			config: `
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        preventAllReplicasOnOneNode: true
+    rule:
+        maxEvictSize: 1
+`,
+			errs: []string{
+				"line 5: field preventAllReplicasOnOneNode not found in type config.ConfigTriggers",
+				"line 6: field rule not found in type config.ConfigSpec",
+			},
+		},
+		{
+			name: "wrong type",
+			config: `
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    rules:
+        maxEvictSize: many
+`,
+			errs: []string{"line 5: cannot unmarshal !!str `many` into int"},
+		},
+		{
+			name: "out of range",
+			config: `
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        minSparedPercentage:
+            cpu: 80
+            pod: -1
+        metricsWeight:
+            memory: 2
+        mode: sometimes
+    rules:
+        maxEvictSize: 0
+        selectionMode: roundRobin
+        recoverTimeout: -1m
+        evictBudget:
+            perWorkload: -1
+        excludeNodes:
+            minAge: -10m
+    leaderElection:
+        leaseDuration: 10s
+        renewDeadline: 10s
+`,
+			errs: []string{
+				"spec.triggers.minSparedPercentage.pod with value -1 is out of range [0, 100]",
+				"spec.triggers.minSparedPercentage.cpu with value 80 is greater than spec.triggers.maxSparedPercentage.cpu with value 70",
+				"spec.triggers.metricsWeight.memory with value 2 is out of range [0, 1]",
+				`spec.triggers.mode with value "sometimes" is neither [event] nor [time]`,
+				"spec.rules.maxEvictSize with value 0 is less than 1",
+				`spec.rules.selectionMode with value "roundRobin" is neither [byNode] nor [fair]`,
+				`spec.rules.recoverTimeout with value "-1m" is negative`,
+				"spec.rules.evictBudget.perWorkload with value -1 is negative",
+				`spec.rules.excludeNodes.minAge with value "-10m" is negative`,
+				`spec.leaderElection.leaseDuration with value "10s" must be greater than spec.leaderElection.renewDeadline with value "10s"`,
+			},
+		},
+		{
+			name: "time of the day",
+			config: `
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        time:
+            from: tonight
+`,
+			errs: []string{`Can't parse "tonight" as a time of the day, expecting a time like 10:00PM or 22:00`},
+		},
+	}
+	for _, test := range tests {
+		_, err := Load([]byte(test.config))
+		if !assert.Error(t, err, test.name) {
+			continue
+		}
+		var msgs []string
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			for _, e := range agg.Errors() {
+				msgs = append(msgs, e.Error())
+			}
+		} else {
+			msgs = append(msgs, err.Error())
+		}
+		assert.Equal(t, test.errs, msgs, test.name)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	base := DefaultConfig()
+	base.Rules.ExcludeNamespaces = []string{"kube-system"}
+
+	conf, err := LoadPolicy(base, []byte(`{"rules":{"nodeSelector":"pool=batch","maxEvictSize":1}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "pool=batch", conf.Rules.NodeSelector)
+	assert.Equal(t, 1, conf.Rules.MaxEvictSize)
+	assert.Equal(t, []string{"kube-system"}, conf.Rules.ExcludeNamespaces)
+	assert.Equal(t, base.Triggers, conf.Triggers)
+
+	for _, empty := range []string{"", "null", "{}"} {
+		conf, err = LoadPolicy(base, []byte(empty))
+		require.NoError(t, err, empty)
+		assert.Equal(t, base, conf, empty)
+	}
+
+	_, err = LoadPolicy(base, []byte(`{"triggers":{"mode":"time"},"rules":{"evictJobPods":true}}`))
+	assert.EqualError(t, err, "[spec.triggers.mode can only be set in the config file, spec.rules.evictJobPods can only be set in the config file]")
+	_, err = LoadPolicy(base, []byte(`{"rules":{"maxEvictSize":0}}`))
+	assert.EqualError(t, err, "spec.rules.maxEvictSize with value 0 is less than 1")
+	_, err = LoadPolicy(base, []byte(`{"rule":{}}`))
+	assert.EqualError(t, err, "line 1: field rule not found in type config.ConfigSpec")
+}
+
+func TestLoadResourceMaps(t *testing.T) {
+	conf, err := Load([]byte(`
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        minSparedPercentage:
+            nvidia.com/gpu: 10
+        maxSparedPercentage:
+            cpu: 80
+        resourceWeights:
+            memory: 2
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ConfigResourcePercentage{"cpu": 30, "memory": 30, ResourcePod: 30, "nvidia.com/gpu": 10}, conf.Triggers.MinSparedPercentage)
+	assert.Equal(t, ConfigResourcePercentage{"cpu": 80, "memory": 70, ResourcePod: 70}, conf.Triggers.MaxSparedPercentage)
+	assert.Equal(t, ConfigResourceWeight{"memory": 2}, conf.Triggers.ResourceWeights)
+	assert.Equal(t, []string{"cpu", "memory", "nvidia.com/gpu", ResourcePod}, Resources(conf.Triggers.MinSparedPercentage, conf.Triggers.MaxSparedPercentage))
+
+	policy, err := LoadPolicy(conf, []byte(`{"triggers":{"minSparedPercentage":{"ephemeral-storage":20}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, policy.Triggers.MinSparedPercentage["ephemeral-storage"])
+	assert.Equal(t, 10.0, policy.Triggers.MinSparedPercentage["nvidia.com/gpu"])
+	_, ok := conf.Triggers.MinSparedPercentage["ephemeral-storage"]
+	assert.False(t, ok, "policy must not modify the base config")
+
+	// The defaults are turned off by values, not by leaving them out.
+	policy, err = LoadPolicy(conf, []byte(`{"triggers":{"minSparedPercentage":{"cpu":0,"memory":0}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ConfigResourcePercentage{"cpu": 0, "memory": 0, ResourcePod: 30, "nvidia.com/gpu": 10}, policy.Triggers.MinSparedPercentage)
+
+	_, err = LoadPolicy(conf, []byte(`{"triggers":{"resourceWeights":{"cpu":-1}}}`))
+	assert.EqualError(t, err, "spec.triggers.resourceWeights.cpu with value -1 is not positive")
+	// A resource weighing 0 would never make a node busy.
+	_, err = LoadPolicy(conf, []byte(`{"triggers":{"resourceWeights":{"cpu":0}}}`))
+	assert.EqualError(t, err, "spec.triggers.resourceWeights.cpu with value 0 is not positive")
+}
+
+func TestLoadScorerParams(t *testing.T) {
+	base := DefaultConfig()
+	assert.Equal(t, "squaredSum", base.Triggers.Scorer.Name)
+	base.Triggers.Scorer = ConfigScorer{Name: "custom", Params: map[string]float64{"a": 1, "b": 2}}
+
+	conf, err := LoadPolicy(base, []byte(`{"triggers":{"scorer":{"params":{"b":3}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ConfigScorer{Name: "custom", Params: map[string]float64{"a": 1, "b": 3}}, conf.Triggers.Scorer)
+
+	// The params of the base scorer are not passed to another one.
+	conf, err = LoadPolicy(base, []byte(`{"triggers":{"scorer":{"name":"max"}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ConfigScorer{Name: "max", Params: map[string]float64{}}, conf.Triggers.Scorer)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/leaderelection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Validate checks the ranges of the values and the constraints between them.
+// All the problems found are returned at once as an aggregate error.
+func (c ConfigSpec) Validate() error {
+	var errs []error
+	errs = append(errs, validatePercentage("spec.triggers.minSparedPercentage", c.Triggers.MinSparedPercentage)...)
+	errs = append(errs, validatePercentage("spec.triggers.maxSparedPercentage", c.Triggers.MaxSparedPercentage)...)
+	for _, name := range Resources(c.Triggers.MinSparedPercentage, c.Triggers.MaxSparedPercentage) {
+		min, hasMin := c.Triggers.MinSparedPercentage[name]
+		max, hasMax := c.Triggers.MaxSparedPercentage[name]
+		if hasMin && hasMax && min > max {
+			errs = append(errs, fmt.Errorf("spec.triggers.minSparedPercentage.%v with value %v is greater than spec.triggers.maxSparedPercentage.%v with value %v",
+				name, min, name, max))
+		}
+	}
+	for _, name := range Resources(ConfigResourcePercentage(c.Triggers.ResourceWeights)) {
+		if weight := c.Triggers.ResourceWeights[name]; weight <= 0 {
+			errs = append(errs, fmt.Errorf("spec.triggers.resourceWeights.%v with value %v is not positive", name, weight))
+		}
+	}
+	errs = append(errs, validateRange("spec.triggers.metricsWeight.cpu", c.Triggers.MetricsWeight.CPU, 0, 1))
+	errs = append(errs, validateRange("spec.triggers.metricsWeight.memory", c.Triggers.MetricsWeight.Memory, 0, 1))
+	if c.Triggers.Mode != "event" && c.Triggers.Mode != "time" {
+		errs = append(errs, fmt.Errorf("spec.triggers.mode with value %q is neither [event] nor [time]", c.Triggers.Mode))
+	}
+	if len(c.Triggers.Time.Windows) == 0 {
+		_, err := parsePositiveDuration("spec.triggers.time.for", c.Triggers.Time.For)
+		errs = append(errs, err)
+	}
+	for i, w := range c.Triggers.Time.Windows {
+		_, err := parsePositiveDuration(fmt.Sprintf("spec.triggers.time.windows[%v].for", i), w.For)
+		errs = append(errs, err)
+	}
+
+	if c.Rules.MaxEvictSize < 1 {
+		errs = append(errs, fmt.Errorf("spec.rules.maxEvictSize with value %v is less than 1", c.Rules.MaxEvictSize))
+	}
+	if c.Rules.SelectionMode != "byNode" && c.Rules.SelectionMode != "fair" {
+		errs = append(errs, fmt.Errorf("spec.rules.selectionMode with value %q is neither [byNode] nor [fair]", c.Rules.SelectionMode))
+	}
+	if recoverTimeout, err := time.ParseDuration(c.Rules.RecoverTimeout); err != nil {
+		errs = append(errs, fmt.Errorf("Can't parse spec.rules.recoverTimeout with value %q as a duration", c.Rules.RecoverTimeout))
+	} else if recoverTimeout < 0 {
+		errs = append(errs, fmt.Errorf("spec.rules.recoverTimeout with value %q is negative", c.Rules.RecoverTimeout))
+	}
+	budgets := []struct {
+		name  string
+		value int
+	}{
+		{"perNode", c.Rules.EvictBudget.PerNode},
+		{"perNamespace", c.Rules.EvictBudget.PerNamespace},
+		{"perWorkload", c.Rules.EvictBudget.PerWorkload},
+		{"perHour", c.Rules.EvictBudget.PerHour},
+	}
+	for _, budget := range budgets {
+		if budget.value < 0 {
+			errs = append(errs, fmt.Errorf("spec.rules.evictBudget.%v with value %v is negative", budget.name, budget.value))
+		}
+	}
+	if minAge, err := time.ParseDuration(c.Rules.ExcludeNodes.MinAge); err != nil {
+		errs = append(errs, fmt.Errorf("Can't parse spec.rules.excludeNodes.minAge with value %q as a duration", c.Rules.ExcludeNodes.MinAge))
+	} else if minAge < 0 {
+		errs = append(errs, fmt.Errorf("spec.rules.excludeNodes.minAge with value %q is negative", c.Rules.ExcludeNodes.MinAge))
+	}
+
+	errs = append(errs, c.LeaderElection.validate()...)
+	return utilerrors.NewAggregate(errs)
+}
+
+func (c ConfigLeaderElection) validate() []error {
+	var errs []error
+	if c.ResourceLock != leaderelection.LeasesResourceLock && c.ResourceLock != leaderelection.ConfigMapsResourceLock {
+		errs = append(errs, fmt.Errorf("spec.leaderElection.resourceLock with value %q is neither [leases] nor [configmaps]", c.ResourceLock))
+	}
+	if c.Namespace == "" {
+		errs = append(errs, fmt.Errorf("spec.leaderElection.namespace is empty"))
+	}
+	if c.Name == "" {
+		errs = append(errs, fmt.Errorf("spec.leaderElection.name is empty"))
+	}
+	leaseDuration, leaseErr := parsePositiveDuration("spec.leaderElection.leaseDuration", c.LeaseDuration)
+	renewDeadline, renewErr := parsePositiveDuration("spec.leaderElection.renewDeadline", c.RenewDeadline)
+	retryPeriod, retryErr := parsePositiveDuration("spec.leaderElection.retryPeriod", c.RetryPeriod)
+	errs = append(errs, leaseErr, renewErr, retryErr)
+	if leaseErr != nil || renewErr != nil || retryErr != nil {
+		return errs
+	}
+	if leaseDuration <= renewDeadline {
+		errs = append(errs, fmt.Errorf("spec.leaderElection.leaseDuration with value %q must be greater than spec.leaderElection.renewDeadline with value %q",
+			c.LeaseDuration, c.RenewDeadline))
+	}
+	if renewDeadline <= time.Duration(leaderelection.JitterFactor*float64(retryPeriod)) {
+		errs = append(errs, fmt.Errorf("spec.leaderElection.renewDeadline with value %q must be greater than %v times spec.leaderElection.retryPeriod with value %q",
+			c.RenewDeadline, leaderelection.JitterFactor, c.RetryPeriod))
+	}
+	return errs
+}
+
+func validatePercentage(field string, p ConfigResourcePercentage) []error {
+	var errs []error
+	for _, name := range Resources(p) {
+		errs = append(errs, validateRange(field+"."+name, p[name], 0, 100))
+	}
+	return errs
+}
+
+func validateRange(field string, value, min, max float64) error {
+	if value < min || value > max {
+		return fmt.Errorf("%v with value %v is out of range [%v, %v]", field, value, min, max)
+	}
+	return nil
+}
+
+func parsePositiveDuration(field, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("Can't parse %v with value %q as a positive duration", field, value)
+	}
+	return duration, nil
+}
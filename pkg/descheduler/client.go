@@ -10,6 +10,16 @@ import (
 )
 
 func CreateClient(kubeConfigFile string) (kubernetes.Interface, error) {
+	cfg, err := CreateRESTConfig(kubeConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
+
+// CreateRESTConfig builds the config of the clients from the kubeconfig file,
+// or from the service account when the file does not exist.
+func CreateRESTConfig(kubeConfigFile string) (*rest.Config, error) {
 	var cfg *rest.Config
 	var err error
 	if _, err = os.Stat(kubeConfigFile); os.IsNotExist(err) {
@@ -23,5 +33,5 @@ func CreateClient(kubeConfigFile string) (kubernetes.Interface, error) {
 			return nil, fmt.Errorf("Failed to build config from kubeconfig file: %v ", err)
 		}
 	}
-	return kubernetes.NewForConfig(cfg)
+	return cfg, nil
 }
@@ -2,14 +2,21 @@ package descheduler
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"time"
 
+	client_v1alpha1 "github.com/lentil1016/descheduler/pkg/client"
 	"github.com/lentil1016/descheduler/pkg/config"
 	"github.com/lentil1016/descheduler/pkg/handler"
+	"github.com/lentil1016/descheduler/pkg/leaderelection"
+	"github.com/lentil1016/descheduler/pkg/logger"
 	"github.com/lentil1016/descheduler/pkg/predictor"
 	"github.com/lentil1016/descheduler/pkg/timer"
 	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
+	policy_v1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -29,7 +36,34 @@ type descheduler struct {
 	queue        workqueue.RateLimitingInterface
 	nodeInformer cache.SharedIndexInformer
 	rsInformer   cache.SharedIndexInformer
-	podInformer  cache.SharedIndexInformer
+	ssInformer   cache.SharedIndexInformer
+	rcInformer   cache.SharedIndexInformer
+	// jobInformer is nil when spec.rules.evictJobPods is disabled
+	jobInformer cache.SharedIndexInformer
+	podInformer cache.SharedIndexInformer
+	pdbInformer cache.SharedIndexInformer
+	pvcInformer cache.SharedIndexInformer
+	pvInformer  cache.SharedIndexInformer
+	nsInformer  cache.SharedIndexInformer
+	// leaderElector is nil when leader election is disabled
+	leaderElector *leaderelection.LeaderElector
+	// handler is nil when descheduling by DeschedulerPolicies
+	handler  *handler.Handler
+	indexers predictor.Indexers
+	// conf is the latest config swapped in
+	conf config.ConfigSpec
+
+	// policyInformer and policyClient are nil unless spec.watchPolicies is enabled
+	policyInformer cache.SharedIndexInformer
+	policyClient   client_v1alpha1.DeschedulerPolicyInterface
+	// policyBase is the config the policies are based on, policyBase and
+	// policies are only used by the worker.
+	policyBase config.ConfigSpec
+	policies   map[string]*policyHandler
+	// evictionHistory is shared by the predictors of the handler and every policy
+	evictionHistory *predictor.EvictionHistory
+	// timer is the timer of the handler and every policy
+	timer handler.Timer
 }
 
 type Descheduler interface {
@@ -40,24 +74,26 @@ func CreateDescheduler() (Descheduler, error) {
 	conf := config.GetConfig()
 
 	kubeconfig := conf.KubeConfigFile
-	fmt.Println("Using kubeconfig file:", kubeconfig)
-	client, err := CreateClient(kubeconfig)
+	logger.Info("Using kubeconfig file", "path", kubeconfig)
+	restConfig, err := CreateRESTConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 	// create a work queue
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
-	// create a node informer with node selector
+	// create a node informer, nodes are selected by the predictor
+	// so that the node selector can be changed by reloading the config
 	nodeInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
-				options.LabelSelector = conf.Rules.NodeSelector
 				return client.CoreV1().Nodes().List(options)
 			},
 			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
-				options.LabelSelector = conf.Rules.NodeSelector
 				return client.CoreV1().Nodes().Watch(options)
 			},
 		},
@@ -79,6 +115,51 @@ func CreateDescheduler() (Descheduler, error) {
 		0,
 		cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
 
+	// create a stateful set informer
+	ssInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.AppsV1().StatefulSets("").List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.AppsV1().StatefulSets("").Watch(options)
+			},
+		},
+		&apps_v1.StatefulSet{},
+		0,
+		cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
+
+	// create a replication controller informer
+	rcInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.CoreV1().ReplicationControllers("").List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().ReplicationControllers("").Watch(options)
+			},
+		},
+		&api_v1.ReplicationController{},
+		0,
+		cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
+
+	// create a job informer if the pods of jobs will be evicted
+	var jobInformer cache.SharedIndexInformer
+	if conf.Rules.EvictJobPods {
+		jobInformer = cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+					return client.BatchV1().Jobs("").List(options)
+				},
+				WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+					return client.BatchV1().Jobs("").Watch(options)
+				},
+			},
+			&batch_v1.Job{},
+			0,
+			cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
+	}
+
 	// create a pod informer
 	podInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
@@ -93,6 +174,62 @@ func CreateDescheduler() (Descheduler, error) {
 		0,
 		cache.Indexers{"byNode": predictor.MetaPodNodeIndexFunc})
 
+	// create a pod disruption budget informer
+	pdbInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.PolicyV1beta1().PodDisruptionBudgets("").List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.PolicyV1beta1().PodDisruptionBudgets("").Watch(options)
+			},
+		},
+		&policy_v1beta1.PodDisruptionBudget{},
+		0,
+		cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
+
+	// create a persistent volume claim informer
+	pvcInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.CoreV1().PersistentVolumeClaims("").List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().PersistentVolumeClaims("").Watch(options)
+			},
+		},
+		&api_v1.PersistentVolumeClaim{},
+		0,
+		cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc})
+
+	// create a persistent volume informer
+	pvInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.CoreV1().PersistentVolumes().List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().PersistentVolumes().Watch(options)
+			},
+		},
+		&api_v1.PersistentVolume{},
+		0,
+		cache.Indexers{})
+
+	// create a namespace informer
+	nsInformer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (k8sruntime.Object, error) {
+				return client.CoreV1().Namespaces().List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Namespaces().Watch(options)
+			},
+		},
+		&api_v1.Namespace{},
+		0,
+		cache.Indexers{})
+
 	nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		// Only handle the update event, because nodes get ready with an update event ultimately.
 		UpdateFunc: func(old, new interface{}) {
@@ -108,18 +245,12 @@ func CreateDescheduler() (Descheduler, error) {
 		},
 	})
 
-	rsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// Only handle the update event, because replicaSet get ready with an update event.
-		UpdateFunc: func(old, new interface{}) {
-			if !predictor.IsReplicaSetReady(old.(*apps_v1.ReplicaSet)) &&
-				predictor.IsReplicaSetReady(new.(*apps_v1.ReplicaSet)) {
-				key, err := cache.MetaNamespaceKeyFunc(old)
-				if err == nil {
-					queue.Add(handler.NewEvent(key, "rsUpdate", "replicaSet"))
-				}
-			}
-		},
-	})
+	addWorkloadEventHandler(rsInformer, queue)
+	addWorkloadEventHandler(ssInformer, queue)
+	addWorkloadEventHandler(rcInformer, queue)
+	if jobInformer != nil {
+		addWorkloadEventHandler(jobInformer, queue)
+	}
 
 	err = timer.InitTimer(func() {
 		queue.Add(handler.NewEvent("", "onTime", "timer"))
@@ -128,63 +259,187 @@ func CreateDescheduler() (Descheduler, error) {
 		return nil, err
 	}
 
-	predictor.Init(nodeInformer.GetIndexer(),
-		rsInformer.GetIndexer(),
-		podInformer.GetIndexer(),
-		client)
+	indexers := predictor.Indexers{
+		NodeIndexer: nodeInformer.GetIndexer(),
+		RSIndexer:   rsInformer.GetIndexer(),
+		SSIndexer:   ssInformer.GetIndexer(),
+		RCIndexer:   rcInformer.GetIndexer(),
+		PodIndexer:  podInformer.GetIndexer(),
+		PDBIndexer:  pdbInformer.GetIndexer(),
+		PVCIndexer:  pvcInformer.GetIndexer(),
+		PVIndexer:   pvInformer.GetIndexer(),
 
-	return &descheduler{
+		NamespaceIndexer: nsInformer.GetIndexer(),
+	}
+	if jobInformer != nil {
+		indexers.JobIndexer = jobInformer.GetIndexer()
+	}
+
+	d := &descheduler{
 		clientset:    client,
 		queue:        queue,
 		nodeInformer: nodeInformer,
 		rsInformer:   rsInformer,
+		ssInformer:   ssInformer,
+		rcInformer:   rcInformer,
+		jobInformer:  jobInformer,
 		podInformer:  podInformer,
-	}, nil
+		pdbInformer:  pdbInformer,
+		pvcInformer:  pvcInformer,
+		pvInformer:   pvInformer,
+		nsInformer:   nsInformer,
+		indexers:     indexers,
+		conf:         conf,
+
+		evictionHistory: predictor.NewEvictionHistory(),
+		timer:           packageTimer{},
+	}
+	if conf.WatchPolicies {
+		// Policies are added by the informer events once the worker starts.
+		d.policyClient, err = client_v1alpha1.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		d.policyInformer = newPolicyInformer(d.policyClient, queue)
+		d.policyBase = conf
+		d.policies = make(map[string]*policyHandler)
+	} else {
+		p := predictor.NewPredictor(indexers, client, conf)
+		p.SetEvictionHistory(d.evictionHistory)
+		d.handler, err = handler.NewHandler(conf, p, d.timer, d.pushEventAfter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conf.LeaderElection.Enabled {
+		d.leaderElector, err = createLeaderElector(conf.LeaderElection, client, d.lead)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
 }
 
 func (d *descheduler) Run(stopCh chan struct{}) {
 	defer runtime.HandleCrash()
 	defer d.queue.ShutDown()
 
-	fmt.Println("Starting descheduler")
+	logger.Info("Starting descheduler")
 	serverStartTime = time.Now().Local()
 
-	{
-		ch := make(chan struct{})
-		defer close(ch)
-		go d.nodeInformer.Run(ch)
-		if !cache.WaitForCacheSync(ch, d.nodeInformer.HasSynced) {
-			runtime.HandleError(fmt.Errorf("Timed out waiting for nodes caches to sync"))
-			return
-		}
+	informers := []struct {
+		name     string
+		informer cache.SharedIndexInformer
+	}{
+		{"nodes", d.nodeInformer},
+		{"replica sets", d.rsInformer},
+		{"stateful sets", d.ssInformer},
+		{"replication controllers", d.rcInformer},
+		{"jobs", d.jobInformer},
+		{"pods", d.podInformer},
+		{"pod disruption budgets", d.pdbInformer},
+		{"persistent volume claims", d.pvcInformer},
+		{"persistent volumes", d.pvInformer},
+		{"namespaces", d.nsInformer},
+		{"descheduler policies", d.policyInformer},
 	}
-	{
-		ch := make(chan struct{})
-		defer close(ch)
-		go d.rsInformer.Run(ch)
-		if !cache.WaitForCacheSync(ch, d.rsInformer.HasSynced) {
-			runtime.HandleError(fmt.Errorf("Timed out waiting for raplica sets caches to sync"))
-			return
+	for _, i := range informers {
+		if i.informer == nil {
+			continue
 		}
-	}
-	{
 		ch := make(chan struct{})
 		defer close(ch)
-		go d.podInformer.Run(ch)
-		if !cache.WaitForCacheSync(ch, d.podInformer.HasSynced) {
-			runtime.HandleError(fmt.Errorf("Timed out waiting for raplica sets caches to sync"))
+		go i.informer.Run(ch)
+		if !cache.WaitForCacheSync(ch, i.informer.HasSynced) {
+			runtime.HandleError(fmt.Errorf("Timed out waiting for %v caches to sync", i.name))
 			return
 		}
 	}
 
-	fmt.Println("descheduler synced and ready")
+	logger.Info("descheduler synced and ready")
+	config.WatchConfig(d.reload)
+
+	if d.leaderElector == nil {
+		d.run(stopCh)
+		return
+	}
+	// Informers keep running while this replica is on standby,
+	// so that it can take over right after being elected.
+	d.leaderElector.Run(stopCh)
+	select {
+	case <-stopCh:
+	default:
+		// The new leader may start a deschedule term while this one is still
+		// in progress here, exit and come back as a standby replica.
+		logger.Info("Leader election lost, exiting")
+		os.Exit(1)
+	}
+}
 
+// run handles the events in workqueue until stopCh is closed
+func (d *descheduler) run(stopCh <-chan struct{}) {
 	// Timer will start if descheduler is configred as time triggered mode
 	timer.RunTimer()
 
 	wait.Until(d.runWorker, time.Second, stopCh)
 }
 
+// lead runs descheduler once this replica is elected, without the events it
+// queued while on standby.
+func (d *descheduler) lead(stopCh <-chan struct{}) {
+	d.dropStaleEvents()
+	d.run(stopCh)
+}
+
+// dropStaleEvents drops the deschedule and recovery events queued while this
+// replica was on standby, the former leader handled them already. The config
+// and policy events are kept in their order.
+func (d *descheduler) dropStaleEvents() {
+	var kept []interface{}
+	dropped := 0
+	for n := d.queue.Len(); n > 0; n-- {
+		item, quit := d.queue.Get()
+		if quit {
+			return
+		}
+		d.queue.Done(item)
+		if event, ok := item.(handler.Event); ok && !event.IsConfig() {
+			dropped++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	for _, item := range kept {
+		d.queue.Add(item)
+	}
+	logger.Info("Dropped the events queued before leading", "dropped", dropped)
+}
+
+// addWorkloadEventHandler pushes the events of workloads getting ready, being
+// scaled or deleted, which descheduler is waiting for while recovering.
+func addWorkloadEventHandler(informer cache.SharedIndexInformer, queue workqueue.RateLimitingInterface) {
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		// Workloads get ready or scaled with an update event.
+		UpdateFunc: func(old, new interface{}) {
+			oldWorkload := predictor.NewWorkload(old)
+			newWorkload := predictor.NewWorkload(new)
+			if (!oldWorkload.IsReady() && newWorkload.IsReady()) ||
+				oldWorkload.Replicas != newWorkload.Replicas {
+				queue.Add(handler.NewEvent(newWorkload.Key(), "update", "workload"))
+			}
+		},
+		// Workload that is deleted will never recover.
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if w := predictor.NewWorkload(obj); w != nil {
+				queue.Add(handler.NewEvent(w.Key(), "delete", "workload"))
+			}
+		},
+	})
+}
+
 func (d *descheduler) runWorker() {
 	for d.processNextItem() {
 		// continue looping
@@ -198,7 +453,44 @@ func (d *descheduler) processNextItem() bool {
 	}
 	defer d.queue.Done(newEvent)
 
-	event := newEvent.(handler.Event)
-	handler.Type(event).Handle(event)
+	switch event := newEvent.(type) {
+	case policyEvent:
+		d.syncPolicy(event.name)
+	case baseConfigEvent:
+		d.rebasePolicies(*event.conf)
+	case handler.Event:
+		d.handle(event)
+	}
 	return true
 }
+
+// handle passes the event to the handler of the config file, or to the
+// handler of every policy in the order of their names.
+func (d *descheduler) handle(event handler.Event) {
+	if d.handler != nil {
+		d.handler.Type(event).Handle(event)
+		return
+	}
+	names := make([]string, 0, len(d.policies))
+	for name, ph := range d.policies {
+		if ph.handler != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := d.policies[name].handler
+		h.Type(event).Handle(event)
+	}
+}
+
+func (d *descheduler) pushEventAfter(event handler.Event, duration time.Duration) {
+	d.queue.AddAfter(event, duration)
+}
+
+// packageTimer is the handler.Timer backed by the timer package.
+type packageTimer struct{}
+
+func (packageTimer) IsOutOfTime() bool {
+	return timer.IsOutOfTime()
+}
@@ -0,0 +1,102 @@
+package descheduler
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/handler"
+	"github.com/lentil1016/descheduler/pkg/predictor"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+)
+
+func TestMain(m *testing.M) {
+	// Log to stderr instead of files, go test shows the logs of failed tests.
+	klog.InitFlags(nil)
+	os.Exit(m.Run())
+}
+
+type fakeTimer struct{}
+
+func (fakeTimer) IsOutOfTime() bool { return false }
+
+// testDescheduler is a descheduler of the config file running against an
+// in-memory cluster with a busy node, without informers.
+type testDescheduler struct {
+	*descheduler
+	clientset *fake.Clientset
+}
+
+func newTestDescheduler(t *testing.T, conf config.ConfigSpec) *testDescheduler {
+	web := fake.NewReplicaSet("default", "web", 3)
+	api := fake.NewReplicaSet("default", "api", 2)
+	byNamespace := cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc}
+	indexers := predictor.Indexers{
+		NodeIndexer: fake.NewIndexer(cache.Indexers{},
+			fake.NewNode("busy", "4", "8Gi", 110),
+			fake.NewNode("spared", "4", "8Gi", 110)),
+		RSIndexer: fake.NewIndexer(byNamespace, web, api),
+		SSIndexer: fake.NewIndexer(byNamespace),
+		RCIndexer: fake.NewIndexer(byNamespace),
+		PodIndexer: fake.NewIndexer(cache.Indexers{"byNode": predictor.MetaPodNodeIndexFunc},
+			fake.SetController(fake.NewPod("default", "web-1", "busy", "1", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "web-2", "busy", "1", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "api-1", "busy", "1", "1Gi"), "ReplicaSet", api),
+			fake.SetController(fake.NewPod("default", "web-3", "spared", "100m", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "api-2", "spared", "100m", "1Gi"), "ReplicaSet", api)),
+		PDBIndexer: fake.NewIndexer(byNamespace),
+	}
+	clientset := fake.NewClientset()
+	clientset.PodIndexer = indexers.PodIndexer
+	d := &descheduler{
+		clientset:       clientset,
+		queue:           workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		indexers:        indexers,
+		conf:            conf,
+		evictionHistory: predictor.NewEvictionHistory(),
+		timer:           fakeTimer{},
+	}
+	p := predictor.NewPredictor(indexers, clientset, conf)
+	p.SetEvictionHistory(d.evictionHistory)
+	h, err := handler.NewHandler(conf, p, d.timer, d.pushEventAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.handler = h
+	return &testDescheduler{descheduler: d, clientset: clientset}
+}
+
+// processQueued handles the events in the queue, not the ones added after a while.
+func (td *testDescheduler) processQueued() {
+	for td.queue.Len() > 0 {
+		td.processNextItem()
+	}
+}
+
+// The events queued on standby were handled by the former leader, replaying
+// them after taking over would deschedule outside of the triggers.
+func TestStandbyEventsDroppedOnTakeover(t *testing.T) {
+	t.Parallel()
+	td := newTestDescheduler(t, config.DefaultConfig())
+	defer td.queue.ShutDown()
+	reloaded := config.DefaultConfig()
+	reloaded.Rules.MaxEvictSize = 1
+	td.queue.Add(handler.NewEvent("busy", "getReady", "node"))
+	td.queue.Add(handler.NewEvent("", "onTime", "timer"))
+	td.queue.Add(handler.NewConfigEvent(reloaded))
+	td.queue.Add(handler.NewEvent("ReplicaSet/default/web", "update", "workload"))
+
+	td.dropStaleEvents()
+	assert.Equal(t, 1, td.queue.Len())
+	td.processQueued()
+	assert.Empty(t, td.clientset.EvictedPods())
+
+	// The events after taking over are handled, with the config reloaded on standby.
+	td.queue.Add(handler.NewEvent("", "onTime", "timer"))
+	td.processQueued()
+	assert.Len(t, td.clientset.EvictedPods(), 1)
+}
@@ -0,0 +1,53 @@
+package descheduler
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/leaderelection"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"k8s.io/client-go/kubernetes"
+)
+
+// createLeaderElector creates a leader elector which calls run when this replica
+// becomes the leader, run must return after the stop channel it gets is closed.
+func createLeaderElector(conf config.ConfigLeaderElection, client kubernetes.Interface, run func(stopCh <-chan struct{})) (*leaderelection.LeaderElector, error) {
+	// Pod name is used as the hostname, it is unique among the replicas.
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get hostname as leader election identity: %v", err)
+	}
+	lock, err := leaderelection.NewResourceLock(conf.ResourceLock, conf.Namespace, conf.Name, client, identity)
+	if err != nil {
+		return nil, err
+	}
+	leaseDuration, err := time.ParseDuration(conf.LeaseDuration)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse spec.leaderElection.leaseDuration: %v", err)
+	}
+	renewDeadline, err := time.ParseDuration(conf.RenewDeadline)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse spec.leaderElection.renewDeadline: %v", err)
+	}
+	retryPeriod, err := time.ParseDuration(conf.RetryPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse spec.leaderElection.retryPeriod: %v", err)
+	}
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(stopCh <-chan struct{}) {
+				logger.Info("Became the leader", "identity", identity, "lock", conf.Namespace+"/"+conf.Name)
+				run(stopCh)
+			},
+			OnStoppedLeading: func() {
+				logger.Info("Stopped leading", "identity", identity, "lock", conf.Namespace+"/"+conf.Name)
+			},
+		},
+	})
+}
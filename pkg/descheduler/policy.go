@@ -0,0 +1,196 @@
+package descheduler
+
+import (
+	"github.com/lentil1016/descheduler/pkg/apis/descheduler/v1alpha1"
+	client_v1alpha1 "github.com/lentil1016/descheduler/pkg/client"
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/handler"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
+	"github.com/lentil1016/descheduler/pkg/predictor"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// policyEvent tells the worker that a DeschedulerPolicy is added, changed or deleted.
+type policyEvent struct {
+	name string
+}
+
+// baseConfigEvent swaps in the config file that the policies are based on.
+type baseConfigEvent struct {
+	conf *config.ConfigSpec
+}
+
+// policyHandler deschedules by a DeschedulerPolicy.
+type policyHandler struct {
+	// handler is nil until a spec of the policy is accepted.
+	handler *handler.Handler
+	// generation is the generation of the spec accepted.
+	generation int64
+	// observedGeneration is the generation of the latest spec, rejected or not.
+	observedGeneration int64
+	// message tells why the latest spec is rejected, empty when it is accepted.
+	message string
+}
+
+func newPolicyInformer(client client_v1alpha1.DeschedulerPolicyInterface, queue workqueue.RateLimitingInterface) cache.SharedIndexInformer {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+				return client.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.Watch(options)
+			},
+		},
+		&v1alpha1.DeschedulerPolicy{},
+		0,
+		cache.Indexers{})
+
+	push := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err == nil {
+			queue.Add(policyEvent{name: key})
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: push,
+		// Only the changes of the spec, the status is written by descheduler itself.
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1alpha1.DeschedulerPolicy).Generation != new.(*v1alpha1.DeschedulerPolicy).Generation {
+				push(new)
+			}
+		},
+		DeleteFunc: push,
+	})
+	return informer
+}
+
+// syncPolicy starts, changes or stops descheduling by the policy. A spec that
+// is rejected is reported in the status, the previous spec accepted is kept.
+func (d *descheduler) syncPolicy(name string) {
+	log := logger.WithValues("policy", name)
+	obj, exists, err := d.policyInformer.GetIndexer().GetByKey(name)
+	if err != nil {
+		log.Error(err, "Failed to get policy")
+		return
+	}
+	ph := d.policies[name]
+	if !exists {
+		if ph != nil {
+			delete(d.policies, name)
+			// a deleted policy is no longer waiting for recovery
+			metrics.Recovering.Set(0, name)
+			log.Info("Policy deleted, stop descheduling by it")
+		}
+		return
+	}
+	policy := obj.(*v1alpha1.DeschedulerPolicy)
+	if ph == nil {
+		ph = &policyHandler{}
+		d.policies[name] = ph
+	} else if ph.observedGeneration == policy.Generation {
+		return
+	}
+	ph.observedGeneration = policy.Generation
+
+	conf, err := loadPolicy(d.policyBase, policy)
+	if err != nil {
+		log.Error(err, "Policy rejected", "generation", policy.Generation)
+		ph.message = err.Error()
+		d.updatePolicyStatus(name)
+		return
+	}
+	ph.message = ""
+	ph.generation = policy.Generation
+	if ph.handler == nil {
+		p := predictor.NewPredictor(d.indexers, d.clientset, conf)
+		p.SetEvictionHistory(d.evictionHistory)
+		ph.handler, err = handler.NewHandler(conf, p, d.timer, d.pushEventAfter)
+		if err != nil {
+			// The config has been validated already.
+			log.Error(err, "Failed to create handler of policy")
+			delete(d.policies, name)
+			return
+		}
+		ph.handler.SetPolicy(name)
+		ph.handler.SetStatusReporter(policyStatusReporter{d: d, name: name})
+		log.Info("Policy added", "generation", policy.Generation)
+	} else {
+		event := handler.NewConfigEvent(conf).ForPolicy(name)
+		ph.handler.Type(event).Handle(event)
+		log.Info("Policy changed", "generation", policy.Generation)
+	}
+	d.updatePolicyStatus(name)
+}
+
+// rebasePolicies swaps in the config file the policies are based on, then
+// applies every policy over it once more.
+func (d *descheduler) rebasePolicies(conf config.ConfigSpec) {
+	d.policyBase = conf
+	for name, ph := range d.policies {
+		ph.observedGeneration = 0
+		d.syncPolicy(name)
+	}
+}
+
+func loadPolicy(base config.ConfigSpec, policy *v1alpha1.DeschedulerPolicy) (config.ConfigSpec, error) {
+	conf, err := config.LoadPolicy(base, policy.Spec.Raw)
+	if err != nil {
+		return conf, err
+	}
+	return conf, ValidateConfig(conf)
+}
+
+// updatePolicyStatus writes the state of the policy and the results of its
+// latest deschedule terms into the status of the DeschedulerPolicy.
+func (d *descheduler) updatePolicyStatus(name string) {
+	ph := d.policies[name]
+	if ph == nil {
+		return
+	}
+	status := v1alpha1.DeschedulerPolicyStatus{
+		State:              v1alpha1.StateInvalid,
+		Message:            ph.message,
+		ObservedGeneration: ph.observedGeneration,
+	}
+	if ph.handler != nil {
+		handlerStatus := ph.handler.Status()
+		if ph.message == "" {
+			status.State = handlerStatus.State
+		}
+		if !handlerStatus.LastRunTime.IsZero() {
+			lastRunTime := metav1.NewTime(handlerStatus.LastRunTime)
+			status.LastRunTime = &lastRunTime
+		}
+		status.LastEvictedPods = handlerStatus.LastEvictedPods
+		status.LastFailedRecoveries = handlerStatus.LastFailedRecoveries
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		policy, err := d.policyClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		policy.Status = status
+		_, err = d.policyClient.UpdateStatus(policy)
+		return err
+	})
+	if err != nil {
+		logger.Error(err, "Failed to update policy status", "policy", name, "state", status.State)
+	}
+}
+
+// policyStatusReporter writes the status of a policy handler into its DeschedulerPolicy.
+type policyStatusReporter struct {
+	d    *descheduler
+	name string
+}
+
+func (r policyStatusReporter) ReportStatus(status handler.Status) {
+	r.d.updatePolicyStatus(r.name)
+}
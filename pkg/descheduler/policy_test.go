@@ -0,0 +1,122 @@
+package descheduler
+
+import (
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/apis/descheduler/v1alpha1"
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchPolicies turns the test descheduler into descheduling by the policies
+// of the returned client.
+func (td *testDescheduler) watchPolicies() *fake.PolicyClient {
+	client := fake.NewPolicyClient()
+	td.handler = nil
+	td.policyClient = client
+	td.policyInformer = newPolicyInformer(client, td.queue)
+	td.policyBase = td.conf
+	td.policies = make(map[string]*policyHandler)
+	return client
+}
+
+// setPolicy creates or changes the policy, and pushes its event as the informer does.
+func (td *testDescheduler) setPolicy(policy *v1alpha1.DeschedulerPolicy) {
+	td.policyClient.(*fake.PolicyClient).Set(policy)
+	td.policyInformer.GetIndexer().Add(policy)
+	td.queue.Add(policyEvent{name: policy.Name})
+}
+
+// deletePolicy deletes the policy, and pushes its event as the informer does.
+func (td *testDescheduler) deletePolicy(name string) {
+	td.policyClient.(*fake.PolicyClient).Delete(name)
+	obj, _, _ := td.policyInformer.GetIndexer().GetByKey(name)
+	td.policyInformer.GetIndexer().Delete(obj)
+	td.queue.Add(policyEvent{name: name})
+}
+
+func TestPolicyAdded(t *testing.T) {
+	t.Parallel()
+	td := newTestDescheduler(t, config.DefaultConfig())
+	defer td.queue.ShutDown()
+	client := td.watchPolicies()
+
+	td.setPolicy(fake.NewPolicy("one", 1, `{"rules":{"maxEvictSize":1}}`))
+	td.processQueued()
+	assert.Equal(t, v1alpha1.DeschedulerPolicyStatus{State: v1alpha1.StateIdle, ObservedGeneration: 1}, client.Status("one"))
+
+	td.queue.Add(handler.NewEvent("", "onTime", "timer"))
+	td.processQueued()
+	evicted := td.clientset.EvictedPods()
+	assert.Len(t, evicted, 1)
+	status := client.Status("one")
+	assert.Equal(t, v1alpha1.StateRecovering, status.State)
+	assert.NotNil(t, status.LastRunTime)
+	assert.Equal(t, evicted, status.LastEvictedPods)
+}
+
+// A rejected spec is reported in the status, and the spec accepted before keeps working.
+func TestPolicyUpdated(t *testing.T) {
+	t.Parallel()
+	td := newTestDescheduler(t, config.DefaultConfig())
+	defer td.queue.ShutDown()
+	client := td.watchPolicies()
+	td.setPolicy(fake.NewPolicy("one", 1, `{"rules":{"maxEvictSize":1}}`))
+	td.processQueued()
+
+	td.setPolicy(fake.NewPolicy("one", 2, `{"rules":{"maxEvictSize":0}}`))
+	td.processQueued()
+	status := client.Status("one")
+	assert.Equal(t, v1alpha1.StateInvalid, status.State)
+	assert.Contains(t, status.Message, "spec.rules.maxEvictSize")
+	assert.Equal(t, int64(2), status.ObservedGeneration)
+	assert.Equal(t, int64(1), td.policies["one"].generation)
+
+	td.setPolicy(fake.NewPolicy("one", 3, `{"rules":{"maxEvictSize":2}}`))
+	td.processQueued()
+	assert.Equal(t, v1alpha1.DeschedulerPolicyStatus{State: v1alpha1.StateIdle, ObservedGeneration: 3}, client.Status("one"))
+
+	td.queue.Add(handler.NewEvent("", "onTime", "timer"))
+	td.processQueued()
+	assert.Len(t, td.clientset.EvictedPods(), 2)
+}
+
+func TestPolicyDeleted(t *testing.T) {
+	t.Parallel()
+	td := newTestDescheduler(t, config.DefaultConfig())
+	defer td.queue.ShutDown()
+	client := td.watchPolicies()
+	td.setPolicy(fake.NewPolicy("one", 1, `{"rules":{"maxEvictSize":1}}`))
+	td.setPolicy(fake.NewPolicy("two", 1, `{"rules":{"nodeSelector":"pool=none"}}`))
+	td.processQueued()
+	require.Len(t, td.policies, 2)
+
+	td.deletePolicy("one")
+	td.processQueued()
+	assert.NotContains(t, td.policies, "one")
+
+	// Only the policy left deschedules, it selects no node.
+	updates := client.StatusUpdates()
+	td.queue.Add(handler.NewEvent("", "onTime", "timer"))
+	td.processQueued()
+	assert.Empty(t, td.clientset.EvictedPods())
+	assert.True(t, client.StatusUpdates() > updates)
+	assert.Equal(t, v1alpha1.StateIdle, client.Status("two").State)
+}
+
+// The status is written once the conflicts with the changes of the policy are over.
+func TestPolicyStatusRetriedOnConflict(t *testing.T) {
+	t.Parallel()
+	td := newTestDescheduler(t, config.DefaultConfig())
+	defer td.queue.ShutDown()
+	client := td.watchPolicies()
+	client.Conflicts = 2
+
+	td.setPolicy(fake.NewPolicy("one", 1, `{}`))
+	td.processQueued()
+	assert.Equal(t, 1, client.StatusUpdates())
+	assert.Equal(t, v1alpha1.StateIdle, client.Status("one").State)
+}
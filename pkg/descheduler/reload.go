@@ -0,0 +1,75 @@
+package descheduler
+
+import (
+	"reflect"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/handler"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/predictor"
+	"github.com/lentil1016/descheduler/pkg/timer"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// reload validates the changed config and swaps it into the timer, the predictor
+// and the handler. The previous config is kept if any of them rejects it.
+// It is only called by the config watcher, one change at a time.
+func (d *descheduler) reload(conf config.ConfigSpec) {
+	// Informers, the client and the leader elector are created at start.
+	keepStartupConfig(d.conf, &conf)
+	if reflect.DeepEqual(conf, d.conf) {
+		return
+	}
+	if err := ValidateConfig(conf); err != nil {
+		logger.Error(err, "Config reload rejected, keeping the previous config")
+		return
+	}
+	if err := timer.ReloadTimer(conf); err != nil {
+		logger.Error(err, "Config reload rejected, keeping the previous config")
+		return
+	}
+	// The predictor and the handler are swapped by the worker between deschedule terms.
+	if d.policyInformer != nil {
+		d.queue.Add(baseConfigEvent{conf: &conf})
+	} else {
+		d.queue.Add(handler.NewConfigEvent(conf))
+	}
+	d.conf = conf
+}
+
+// ValidateConfig checks the config like loading it does, then checks
+// that the timer, the predictor and the handler can all work with it.
+func ValidateConfig(conf config.ConfigSpec) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, validate := range []func(config.ConfigSpec) error{
+		timer.ValidateConfig,
+		predictor.ValidateConfig,
+		handler.ValidateConfig,
+	} {
+		errs = append(errs, validate(conf))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// keepStartupConfig restores the fields that only take effect after restarting descheduler.
+func keepStartupConfig(current config.ConfigSpec, changed *config.ConfigSpec) {
+	if changed.KubeConfigFile != current.KubeConfigFile {
+		logger.Info("Changing spec.kubeconfig requires restarting descheduler, ignoring it")
+		changed.KubeConfigFile = current.KubeConfigFile
+	}
+	if changed.Rules.EvictJobPods != current.Rules.EvictJobPods {
+		logger.Info("Changing spec.rules.evictJobPods requires restarting descheduler, ignoring it")
+		changed.Rules.EvictJobPods = current.Rules.EvictJobPods
+	}
+	if changed.WatchPolicies != current.WatchPolicies {
+		logger.Info("Changing spec.watchPolicies requires restarting descheduler, ignoring it")
+		changed.WatchPolicies = current.WatchPolicies
+	}
+	if !reflect.DeepEqual(changed.LeaderElection, current.LeaderElection) {
+		logger.Info("Changing spec.leaderElection requires restarting descheduler, ignoring it")
+		changed.LeaderElection = current.LeaderElection
+	}
+}
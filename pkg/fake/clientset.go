@@ -0,0 +1,174 @@
+package fake
+
+import (
+	"sync"
+
+	api_v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	policy_v1beta1 "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// k8s.io/client-go/kubernetes/fake is not vendored to avoid the huge vendoring
+// issues. Clientset implements the few requests descheduler sends, calling
+// any other method of kubernetes.Interface panics.
+
+// Clientset is an in-memory kubernetes.Interface recording evictions, Events
+// and patches. It is safe to be used by parallel tests, one for each test.
+type Clientset struct {
+	kubernetes.Interface
+
+	// PodIndexer, if set, has the evicted pods deleted from it,
+	// as the informer would after the pods are gone.
+	PodIndexer cache.Indexer
+	// EvictError, if set, is returned by the eviction of the pods it returns non-nil for.
+	EvictError func(eviction *policy.Eviction) error
+
+	mutex     sync.Mutex
+	evictions []string
+	events    []*api_v1.Event
+	patches   map[string][]byte
+}
+
+// NewClientset creates an empty Clientset.
+func NewClientset() *Clientset {
+	return &Clientset{patches: map[string][]byte{}}
+}
+
+// EvictedPods returns the namespace/name of the evicted pods, in eviction order.
+func (c *Clientset) EvictedPods() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string{}, c.evictions...)
+}
+
+// CreatedEvents returns the Events created.
+func (c *Clientset) CreatedEvents() []*api_v1.Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*api_v1.Event{}, c.events...)
+}
+
+// PodPatch returns the latest patch sent to the pod with the namespace/name key.
+func (c *Clientset) PodPatch(key string) []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.patches[key]
+}
+
+func (c *Clientset) CoreV1() core_v1.CoreV1Interface {
+	return &coreV1{clientset: c}
+}
+
+func (c *Clientset) PolicyV1beta1() policy_v1beta1.PolicyV1beta1Interface {
+	return &policyV1beta1{clientset: c}
+}
+
+func (c *Clientset) Policy() policy_v1beta1.PolicyV1beta1Interface {
+	return c.PolicyV1beta1()
+}
+
+func (c *Clientset) Discovery() discovery.DiscoveryInterface {
+	return &fakeDiscovery{}
+}
+
+type coreV1 struct {
+	core_v1.CoreV1Interface
+	clientset *Clientset
+}
+
+func (c *coreV1) Events(namespace string) core_v1.EventInterface {
+	return &events{namespace: namespace, clientset: c.clientset}
+}
+
+func (c *coreV1) Pods(namespace string) core_v1.PodInterface {
+	return &pods{namespace: namespace, clientset: c.clientset}
+}
+
+type events struct {
+	core_v1.EventInterface
+	namespace string
+	clientset *Clientset
+}
+
+func (e *events) Create(event *api_v1.Event) (*api_v1.Event, error) {
+	e.clientset.mutex.Lock()
+	defer e.clientset.mutex.Unlock()
+	event = event.DeepCopy()
+	event.Namespace = e.namespace
+	e.clientset.events = append(e.clientset.events, event)
+	return event, nil
+}
+
+type pods struct {
+	core_v1.PodInterface
+	namespace string
+	clientset *Clientset
+}
+
+func (p *pods) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*api_v1.Pod, error) {
+	p.clientset.mutex.Lock()
+	defer p.clientset.mutex.Unlock()
+	p.clientset.patches[p.namespace+"/"+name] = data
+	return &api_v1.Pod{ObjectMeta: v1.ObjectMeta{Namespace: p.namespace, Name: name}}, nil
+}
+
+type policyV1beta1 struct {
+	policy_v1beta1.PolicyV1beta1Interface
+	clientset *Clientset
+}
+
+func (p *policyV1beta1) Evictions(namespace string) policy_v1beta1.EvictionInterface {
+	return &evictions{namespace: namespace, clientset: p.clientset}
+}
+
+type evictions struct {
+	policy_v1beta1.EvictionInterface
+	namespace string
+	clientset *Clientset
+}
+
+func (e *evictions) Evict(eviction *policy.Eviction) error {
+	c := e.clientset
+	if c.EvictError != nil {
+		if err := c.EvictError(eviction); err != nil {
+			return err
+		}
+	}
+	key := e.namespace + "/" + eviction.Name
+	c.mutex.Lock()
+	c.evictions = append(c.evictions, key)
+	c.mutex.Unlock()
+	if c.PodIndexer != nil {
+		if obj, exists, _ := c.PodIndexer.GetByKey(key); exists {
+			c.PodIndexer.Delete(obj)
+		}
+	}
+	return nil
+}
+
+// fakeDiscovery tells the server supports the policy/v1beta1 eviction subresource.
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (d *fakeDiscovery) ServerGroups() (*v1.APIGroupList, error) {
+	policyVersion := v1.GroupVersionForDiscovery{GroupVersion: "policy/v1beta1", Version: "v1beta1"}
+	return &v1.APIGroupList{Groups: []v1.APIGroup{{
+		Name:             "policy",
+		Versions:         []v1.GroupVersionForDiscovery{policyVersion},
+		PreferredVersion: policyVersion,
+	}}}, nil
+}
+
+func (d *fakeDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*v1.APIResourceList, error) {
+	return &v1.APIResourceList{
+		GroupVersion: groupVersion,
+		APIResources: []v1.APIResource{{Name: "pods/eviction", Kind: "Eviction"}},
+	}, nil
+}
@@ -0,0 +1,145 @@
+package fake
+
+import (
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewNode creates a ready and schedulable node with the allocatable resources.
+func NewNode(name, cpu, memory string, pods int64) *api_v1.Node {
+	allocatable := api_v1.ResourceList{
+		api_v1.ResourceCPU:    resource.MustParse(cpu),
+		api_v1.ResourceMemory: resource.MustParse(memory),
+		api_v1.ResourcePods:   *resource.NewQuantity(pods, resource.DecimalSI),
+	}
+	return &api_v1.Node{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			UID:    types.UID("node-" + name),
+			Labels: map[string]string{"kubernetes.io/hostname": name},
+		},
+		Status: api_v1.NodeStatus{
+			Capacity:    allocatable,
+			Allocatable: allocatable,
+			Conditions: []api_v1.NodeCondition{
+				{Type: api_v1.NodeReady, Status: api_v1.ConditionTrue},
+			},
+		},
+	}
+}
+
+// NewReplicaSet creates a ReplicaSet with all its replicas ready.
+func NewReplicaSet(namespace, name string, replicas int32) *apps_v1.ReplicaSet {
+	return &apps_v1.ReplicaSet{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       types.UID("rs-" + namespace + "-" + name),
+		},
+		Spec: apps_v1.ReplicaSetSpec{
+			Replicas: &replicas,
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: apps_v1.ReplicaSetStatus{
+			Replicas:      replicas,
+			ReadyReplicas: replicas,
+		},
+	}
+}
+
+// NewPod creates a running pod on the node, with one container requesting the resources.
+func NewPod(namespace, name, nodeName, cpu, memory string) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       types.UID("pod-" + namespace + "-" + name),
+			Labels:    map[string]string{},
+		},
+		Spec: api_v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []api_v1.Container{{
+				Name: "app",
+				Resources: api_v1.ResourceRequirements{
+					Requests: api_v1.ResourceList{
+						api_v1.ResourceCPU:    resource.MustParse(cpu),
+						api_v1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+		Status: api_v1.PodStatus{Phase: api_v1.PodRunning},
+	}
+}
+
+// SetController makes the owner of the kind the controller of the pod,
+// and copies the "app" label of the owner's selector to the pod.
+func SetController(pod *api_v1.Pod, kind string, owner v1.Object) *api_v1.Pod {
+	isController := true
+	pod.OwnerReferences = append(pod.OwnerReferences, v1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       owner.GetName(),
+		UID:        owner.GetUID(),
+		Controller: &isController,
+	})
+	pod.Labels["app"] = owner.GetName()
+	return pod
+}
+
+// SetTaint adds the taint to the node.
+func SetTaint(node *api_v1.Node, key string, effect api_v1.TaintEffect) *api_v1.Node {
+	node.Spec.Taints = append(node.Spec.Taints, api_v1.Taint{Key: key, Effect: effect})
+	return node
+}
+
+// SetToleration makes the pod tolerate the taints with the key.
+func SetToleration(pod *api_v1.Pod, key string) *api_v1.Pod {
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, api_v1.Toleration{Key: key, Operator: api_v1.TolerationOpExists})
+	return pod
+}
+
+// SetAntiAffinity makes the pod refuse to run in the same topology domain as the pods with the "app" label.
+func SetAntiAffinity(pod *api_v1.Pod, app, topologyKey string) *api_v1.Pod {
+	pod.Spec.Affinity = &api_v1.Affinity{
+		PodAntiAffinity: &api_v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []api_v1.PodAffinityTerm{{
+				LabelSelector: &v1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+				TopologyKey:   topologyKey,
+			}},
+		},
+	}
+	return pod
+}
+
+// NewPodDisruptionBudget creates a budget selecting the pods with the "app" label,
+// which allows the given number of disruptions.
+func NewPodDisruptionBudget(namespace, name, app string, allowed int32) *policy.PodDisruptionBudget {
+	return &policy.PodDisruptionBudget{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: policy.PodDisruptionBudgetSpec{
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+		},
+		Status: policy.PodDisruptionBudgetStatus{
+			PodDisruptionsAllowed: allowed,
+		},
+	}
+}
+
+// NewIndexer creates an indexer with the indexes holding the objects,
+// keyed by namespace/name as the informers do.
+func NewIndexer(indexers cache.Indexers, objs ...interface{}) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+	for _, obj := range objs {
+		indexer.Add(obj)
+	}
+	return indexer
+}
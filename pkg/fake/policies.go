@@ -0,0 +1,122 @@
+package fake
+
+import (
+	"sync"
+
+	"github.com/lentil1016/descheduler/pkg/apis/descheduler/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var policyResource = v1alpha1.SchemeGroupVersion.WithResource(v1alpha1.Resource).GroupResource()
+
+// PolicyClient is an in-memory DeschedulerPolicyInterface. The policies are
+// created, changed and deleted by Set and Delete, as users would through the
+// API server, and their status is written by UpdateStatus.
+type PolicyClient struct {
+	// Conflicts is the number of the next status updates answered with a
+	// conflict, as if the policy was changed in the meantime.
+	Conflicts int
+
+	mutex         sync.Mutex
+	policies      map[string]*v1alpha1.DeschedulerPolicy
+	statusUpdates int
+}
+
+// NewPolicyClient creates a PolicyClient having the policies.
+func NewPolicyClient(policies ...*v1alpha1.DeschedulerPolicy) *PolicyClient {
+	c := &PolicyClient{policies: map[string]*v1alpha1.DeschedulerPolicy{}}
+	for _, policy := range policies {
+		c.Set(policy)
+	}
+	return c
+}
+
+// NewPolicy creates a DeschedulerPolicy with the spec in JSON.
+func NewPolicy(name string, generation int64, spec string) *v1alpha1.DeschedulerPolicy {
+	return &v1alpha1.DeschedulerPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       name,
+			Generation: generation,
+		},
+		Spec: runtime.RawExtension{Raw: []byte(spec)},
+	}
+}
+
+// Set creates the policy or replaces its spec, keeping the status written.
+func (c *PolicyClient) Set(policy *v1alpha1.DeschedulerPolicy) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	policy = policy.DeepCopy()
+	if old, ok := c.policies[policy.Name]; ok {
+		policy.Status = old.Status
+	}
+	c.policies[policy.Name] = policy
+}
+
+// Delete deletes the policy.
+func (c *PolicyClient) Delete(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.policies, name)
+}
+
+// Status returns the status written into the policy.
+func (c *PolicyClient) Status(name string) v1alpha1.DeschedulerPolicyStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if policy, ok := c.policies[name]; ok {
+		return policy.Status
+	}
+	return v1alpha1.DeschedulerPolicyStatus{}
+}
+
+// StatusUpdates returns the number of status updates accepted.
+func (c *PolicyClient) StatusUpdates() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.statusUpdates
+}
+
+func (c *PolicyClient) Get(name string, opts v1.GetOptions) (*v1alpha1.DeschedulerPolicy, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	policy, ok := c.policies[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(policyResource, name)
+	}
+	return policy.DeepCopy(), nil
+}
+
+func (c *PolicyClient) List(opts v1.ListOptions) (*v1alpha1.DeschedulerPolicyList, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	list := &v1alpha1.DeschedulerPolicyList{}
+	for _, policy := range c.policies {
+		list.Items = append(list.Items, *policy.DeepCopy())
+	}
+	return list, nil
+}
+
+// Watch returns a watcher that sends no event.
+func (c *PolicyClient) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return watch.NewFake(), nil
+}
+
+func (c *PolicyClient) UpdateStatus(policy *v1alpha1.DeschedulerPolicy) (*v1alpha1.DeschedulerPolicy, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.Conflicts > 0 {
+		c.Conflicts--
+		return nil, apierrors.NewConflict(policyResource, policy.Name, nil)
+	}
+	stored, ok := c.policies[policy.Name]
+	if !ok {
+		return nil, apierrors.NewNotFound(policyResource, policy.Name)
+	}
+	policy.Status.DeepCopyInto(&stored.Status)
+	c.statusUpdates++
+	return stored.DeepCopy(), nil
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+)
+
+type configHandler struct {
+	h *Handler
+}
+
+// Handle swaps the config of the event into the predictor and the handler. The
+// timeout of an ongoing recovery is kept, the new one applies from the next recovery.
+func (ch *configHandler) Handle(event Event) {
+	h := ch.h
+	recoverTimeout, err := parseRecoverTimeout(*event.conf)
+	if err != nil {
+		logger.Error(err, "Config reload rejected, keeping the previous config")
+		return
+	}
+	h.recoverTimeout = recoverTimeout
+	h.dryRun = event.conf.DryRun
+	h.predictor.SetConfig(*event.conf)
+	logger.Info("Config reloaded")
+}
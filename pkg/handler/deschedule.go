@@ -1,41 +1,68 @@
 package handler
 
 import (
-	"fmt"
+	"time"
 
-	"github.com/lentil1016/descheduler/pkg/predictor"
-	"github.com/lentil1016/descheduler/pkg/timer"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
 )
 
 type descheduleHandler struct {
+	h *Handler
 }
 
 func (dh *descheduleHandler) Handle(event Event) {
-	if timer.IsOutOfTime() {
-		fmt.Println("Deschedule event aborted by timer")
+	h := dh.h
+	h.termID = newTermID()
+	log := logger.WithValues("term", h.termID)
+	if h.timer.IsOutOfTime() {
+		log.V(2).Info("Deschedule event aborted by timer")
 		return
 	}
+	metrics.DescheduleTerms.Inc(event.resourceType)
+	h.isDescheduling = true
+	h.lastRunTime = time.Now()
+	h.reportStatus()
+	defer func() {
+		h.isDescheduling = false
+		h.reportStatus()
+	}()
+	log.Info("Deschedule term started", "trigger", event.resourceType, "key", event.key)
 
 	// get busy nodes.
-	busyNodes, ok := predictor.GetBusyNodes()
+	busyNodes, ok := h.predictor.GetBusyNodes(log)
 	if !ok {
 		return
 	}
-	fmt.Println("descheduleHandler: Deschedule Triggered, start picking Pods")
-	pods, err := predictor.GetEvictPods(busyNodes)
+	log.Info("Deschedule triggered, start picking pods", "busyNodes", len(busyNodes))
+	pods, err := h.predictor.GetEvictPods(log, busyNodes)
 	if err != nil {
-		fmt.Println(err)
+		log.Error(err, "Picking pods failed")
 		return
 	}
-	fmt.Println("descheduleHandler: Pods picking done, start to evict")
-	predictor.Evict(pods)
-	recoveringMap = make(map[string]bool, len(pods))
-	for _, pod := range pods {
-		rsName := predictor.GetPodReplicaSetName(pod)
-		if rsName != "" {
-			recoveringMap[rsName] = true
+	log.Info("Pods picking done, start to evict", "pods", len(pods))
+	evicted := h.predictor.Evict(log, pods)
+	if len(evicted) > 0 {
+		h.lastEvictedPods = make([]string, 0, len(evicted))
+		for _, pod := range evicted {
+			h.lastEvictedPods = append(h.lastEvictedPods, pod.Namespace+"/"+pod.Name)
 		}
 	}
-	isRecovering = true
-	fmt.Println("descheduleHandler: Eviction is finished, waiting for recovering")
+	if h.dryRun {
+		log.Info("Dry run is finished, nothing to recover", "evicted", len(evicted))
+		return
+	}
+	h.recoveringMap = make(map[string]bool, len(evicted))
+	for _, pod := range evicted {
+		workloadKey := h.predictor.GetPodWorkloadKey(pod)
+		if workloadKey != "" {
+			h.recoveringMap[workloadKey] = true
+		}
+	}
+	if len(h.recoveringMap) == 0 {
+		log.Info("No workload pod has been evicted, nothing to recover", "evicted", len(evicted))
+		return
+	}
+	h.startRecovering()
+	log.Info("Eviction is finished, waiting for recovering", "evicted", len(evicted), "workloads", len(h.recoveringMap))
 }
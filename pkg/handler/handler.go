@@ -1,19 +1,112 @@
 package handler
 
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/apis/descheduler/v1alpha1"
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/predictor"
+)
+
+// Event is the item of the workqueue, events of the same object are equal so
+// that the queue merges them, it must not carry anything unique to each event.
 type Event struct {
 	key          string
 	eventType    string
 	resourceType string
+	// conf is the config to swap in, only set in config events.
+	conf *config.ConfigSpec
+	// policy is the name of the only DeschedulerPolicy whose handler handles
+	// the event, empty events are handled by every handler.
+	policy string
+}
+
+// StatusReporter is told the status of the handler every time it changes.
+type StatusReporter interface {
+	ReportStatus(status Status)
+}
+
+// Status is the state of the handler and the results of the latest deschedule terms.
+type Status struct {
+	// State is one of idle, descheduling and recovering.
+	State string
+	// LastRunTime is the time the latest deschedule term started.
+	LastRunTime time.Time
+	// LastEvictedPods are the keys of the pods evicted by the latest deschedule term that evicted any.
+	LastEvictedPods []string
+	// LastFailedRecoveries are the keys of the workloads that failed to recover in the latest abandoned recovery.
+	LastFailedRecoveries []string
 }
 
 type eventHandler interface {
 	Handle(event Event)
 }
 
-// There is no race condition on this value because there is only one worker thread
-// So only one event will be handled at a time
-var isRecovering = false
-var recoveringMap map[string]bool
+// Timer tells if descheduling is allowed at the moment.
+type Timer interface {
+	IsOutOfTime() bool
+}
+
+// Handler keeps the recovering state between events. There is no race condition
+// on its fields because there is only one worker thread, so only one event will
+// be handled at a time.
+type Handler struct {
+	predictor *predictor.Predictor
+	timer     Timer
+	// policy is the name of the DeschedulerPolicy the handler deschedules by,
+	// empty when descheduling by the config file.
+	policy         string
+	statusReporter StatusReporter
+
+	isDescheduling  bool
+	lastRunTime     time.Time
+	lastEvictedPods []string
+
+	isRecovering    bool
+	recoveringMap   map[string]bool
+	recoveringSince time.Time
+	// termID correlates the logs of the latest deschedule term, and of the
+	// recovery from it.
+	termID string
+	// recoveringTerm increases every time descheduler starts recovering,
+	// it tells the timeout events of the previous recoveries apart.
+	recoveringTerm int
+
+	// dryRun skips recovering, no workload has really lost a pod.
+	dryRun bool
+	// recoverTimeout is the duration after which the recovery is abandoned, 0 means never.
+	recoverTimeout       time.Duration
+	lastFailedRecoveries []string
+	pushEventAfter       func(event Event, duration time.Duration)
+}
+
+// termSeq is increased by the handlers of every policy.
+var termSeq uint64
+
+func NewHandler(conf config.ConfigSpec, p *predictor.Predictor, t Timer,
+	pushEventAfterHandle func(event Event, duration time.Duration)) (*Handler, error) {
+	recoverTimeout, err := parseRecoverTimeout(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		predictor:      p,
+		timer:          t,
+		dryRun:         conf.DryRun,
+		recoverTimeout: recoverTimeout,
+		// Set the function which will be called to push an event after a while.
+		pushEventAfter: pushEventAfterHandle,
+	}, nil
+}
+
+// newTermID returns a new id of deschedule term, unique in the process.
+func newTermID() string {
+	seq := atomic.AddUint64(&termSeq, 1)
+	return strconv.FormatInt(time.Now().Unix(), 36) + "-" + strconv.FormatUint(seq, 10)
+}
 
 func NewEvent(key, eventType, resourceType string) Event {
 	return Event{
@@ -23,22 +116,108 @@ func NewEvent(key, eventType, resourceType string) Event {
 	}
 }
 
-func Type(event Event) eventHandler {
-	if isRecovering {
-		// Handle recover event when the replica sets is recovering
-		if event.resourceType == "replicaSet" {
-			return &recoverHandler{}
+// IsConfig tells if the event swaps a config in, rather than triggering or
+// recovering from a deschedule term.
+func (e Event) IsConfig() bool {
+	return e.resourceType == "config"
+}
+
+// ForPolicy returns the event that is only handled by the handler of the DeschedulerPolicy.
+func (e Event) ForPolicy(name string) Event {
+	e.policy = name
+	return e
+}
+
+// NewConfigEvent creates an event swapping the config in between deschedule terms.
+func NewConfigEvent(conf config.ConfigSpec) Event {
+	event := NewEvent("", "reload", "config")
+	event.conf = &conf
+	return event
+}
+
+// ValidateConfig checks if the handler can work with the rules of the config.
+func ValidateConfig(conf config.ConfigSpec) error {
+	_, err := parseRecoverTimeout(conf)
+	return err
+}
+
+func parseRecoverTimeout(conf config.ConfigSpec) (time.Duration, error) {
+	recoverTimeout, err := time.ParseDuration(conf.Rules.RecoverTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("Please check config file. Can't parse spec.rules.recoverTimeout with value %v: %v", conf.Rules.RecoverTimeout, err)
+	}
+	return recoverTimeout, nil
+}
+
+func (h *Handler) Type(event Event) eventHandler {
+	if event.policy != "" && event.policy != h.policy {
+		return defaultHandler{}
+	}
+	// Config is swapped in whether recovering or not.
+	if event.resourceType == "config" {
+		return &configHandler{h}
+	}
+	if h.isRecovering {
+		// Handle recover event when the workloads are recovering
+		if event.resourceType == "workload" {
+			return &recoverHandler{h}
+		} else if event.resourceType == "recovery" {
+			return &recoverTimeoutHandler{h}
 		}
 	} else {
-		// Handle deschedule event only when replicas number of the replica sets
+		// Handle deschedule event only when replicas number of the workloads
 		// that is being descheduled last time have recovered.
 		if event.resourceType == "timer" || event.resourceType == "node" {
-			return &descheduleHandler{}
+			return &descheduleHandler{h}
 		}
 	}
 	return defaultHandler{}
 }
 
+// IsRecovering checks if the handler is waiting for evicted workloads to recover.
+func (h *Handler) IsRecovering() bool {
+	return h.isRecovering
+}
+
+// LastFailedRecoveries returns the keys of the workloads that failed to
+// recover in the latest abandoned recovery.
+func (h *Handler) LastFailedRecoveries() []string {
+	return h.lastFailedRecoveries
+}
+
+// SetPolicy names the DeschedulerPolicy the handler deschedules by, so that
+// the events it pushes are only handled by itself.
+func (h *Handler) SetPolicy(name string) {
+	h.policy = name
+}
+
+// SetStatusReporter sets the reporter that is told every change of the status.
+func (h *Handler) SetStatusReporter(r StatusReporter) {
+	h.statusReporter = r
+}
+
+// Status returns the state of the handler and the results of the latest deschedule terms.
+func (h *Handler) Status() Status {
+	state := v1alpha1.StateIdle
+	if h.isDescheduling {
+		state = v1alpha1.StateDescheduling
+	} else if h.isRecovering {
+		state = v1alpha1.StateRecovering
+	}
+	return Status{
+		State:                state,
+		LastRunTime:          h.lastRunTime,
+		LastEvictedPods:      h.lastEvictedPods,
+		LastFailedRecoveries: h.lastFailedRecoveries,
+	}
+}
+
+func (h *Handler) reportStatus() {
+	if h.statusReporter != nil {
+		h.statusReporter.ReportStatus(h.Status())
+	}
+}
+
 type defaultHandler struct{}
 
 func (dh defaultHandler) Handle(event Event) {
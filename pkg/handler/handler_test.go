@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/metrics"
+	"github.com/lentil1016/descheduler/pkg/predictor"
+	"github.com/stretchr/testify/assert"
+	apps_v1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+func TestMain(m *testing.M) {
+	// Log to stderr instead of files, go test shows the logs of failed tests.
+	klog.InitFlags(nil)
+	os.Exit(m.Run())
+}
+
+type fakeTimer struct {
+	outOfTime bool
+}
+
+func (ft *fakeTimer) IsOutOfTime() bool {
+	return ft.outOfTime
+}
+
+type delayedEvent struct {
+	event    Event
+	duration time.Duration
+}
+
+// testCluster is a Handler running against an in-memory cluster.
+type testCluster struct {
+	*Handler
+	clientset *fake.Clientset
+	rsIndexer cache.Indexer
+	timer     *fakeTimer
+	delayed   []delayedEvent
+}
+
+// newTestCluster creates a busy node running two pods of web and one pod of api,
+// and a spared node running the other pods of them.
+func newTestCluster(t *testing.T, conf config.ConfigSpec) *testCluster {
+	web := fake.NewReplicaSet("default", "web", 3)
+	api := fake.NewReplicaSet("default", "api", 2)
+	byNamespace := cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc}
+	indexers := predictor.Indexers{
+		NodeIndexer: fake.NewIndexer(cache.Indexers{},
+			fake.NewNode("busy", "4", "8Gi", 110),
+			fake.NewNode("spared", "4", "8Gi", 110)),
+		RSIndexer: fake.NewIndexer(byNamespace, web, api),
+		SSIndexer: fake.NewIndexer(byNamespace),
+		RCIndexer: fake.NewIndexer(byNamespace),
+		PodIndexer: fake.NewIndexer(cache.Indexers{"byNode": predictor.MetaPodNodeIndexFunc},
+			fake.SetController(fake.NewPod("default", "web-1", "busy", "1", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "web-2", "busy", "1", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "api-1", "busy", "1", "1Gi"), "ReplicaSet", api),
+			fake.SetController(fake.NewPod("default", "web-3", "spared", "100m", "1Gi"), "ReplicaSet", web),
+			fake.SetController(fake.NewPod("default", "api-2", "spared", "100m", "1Gi"), "ReplicaSet", api)),
+		PDBIndexer: fake.NewIndexer(byNamespace),
+	}
+	clientset := fake.NewClientset()
+	clientset.PodIndexer = indexers.PodIndexer
+	tc := &testCluster{
+		clientset: clientset,
+		rsIndexer: indexers.RSIndexer,
+		timer:     &fakeTimer{},
+	}
+	h, err := NewHandler(conf, predictor.NewPredictor(indexers, clientset, conf), tc.timer,
+		func(event Event, duration time.Duration) {
+			tc.delayed = append(tc.delayed, delayedEvent{event, duration})
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.Handler = h
+	return tc
+}
+
+func (tc *testCluster) handle(event Event) {
+	tc.Type(event).Handle(event)
+}
+
+// setReadyReplicas updates the ReplicaSet and pushes the update event as the informer does.
+func (tc *testCluster) setReadyReplicas(name string, ready int32) {
+	obj, _, _ := tc.rsIndexer.GetByKey("default/" + name)
+	rs := obj.(*apps_v1.ReplicaSet).DeepCopy()
+	rs.Status.ReadyReplicas = ready
+	tc.rsIndexer.Update(rs)
+	tc.handle(NewEvent("ReplicaSet/default/"+name, "update", "workload"))
+}
+
+func TestDescheduleRecoverCycle(t *testing.T) {
+	t.Parallel()
+	tc := newTestCluster(t, config.DefaultConfig())
+
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Len(t, tc.clientset.EvictedPods(), 2)
+	assert.True(t, tc.IsRecovering())
+	// The timeout of the recovery is scheduled.
+	if assert.Len(t, tc.delayed, 1) {
+		assert.Equal(t, 10*time.Minute, tc.delayed[0].duration)
+	}
+
+	// Deschedule events are ignored while recovering.
+	tc.handle(NewEvent("busy", "getReady", "node"))
+	assert.Len(t, tc.clientset.EvictedPods(), 2)
+
+	// The ReplicaSets lost a pod each, and get them back one by one.
+	tc.setReadyReplicas("web", 2)
+	tc.setReadyReplicas("api", 1)
+	assert.True(t, tc.IsRecovering())
+	tc.setReadyReplicas("web", 3)
+	assert.True(t, tc.IsRecovering())
+	assert.Len(t, tc.delayed, 1)
+	tc.setReadyReplicas("api", 2)
+	assert.False(t, tc.IsRecovering())
+	if assert.Len(t, tc.delayed, 2) {
+		assert.Equal(t, delayedEvent{NewEvent("", "onTime", "timer"), 5 * time.Second}, tc.delayed[1])
+	}
+
+	// The timeout of the finished recovery is ignored.
+	tc.handle(tc.delayed[0].event)
+	assert.Empty(t, tc.LastFailedRecoveries())
+
+	// The evicted pods are gone, the busy node is no longer busy in the next term.
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Len(t, tc.clientset.EvictedPods(), 2)
+	assert.False(t, tc.IsRecovering())
+}
+
+func TestRecoverDeletedWorkload(t *testing.T) {
+	t.Parallel()
+	tc := newTestCluster(t, config.DefaultConfig())
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.True(t, tc.IsRecovering())
+
+	obj, _, _ := tc.rsIndexer.GetByKey("default/web")
+	tc.rsIndexer.Delete(obj)
+	tc.handle(NewEvent("ReplicaSet/default/web", "delete", "workload"))
+	assert.True(t, tc.IsRecovering())
+	tc.handle(NewEvent("ReplicaSet/default/api", "delete", "workload"))
+	assert.False(t, tc.IsRecovering())
+}
+
+func TestRecoverTimeout(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.RecoverTimeout = "1m"
+	tc := newTestCluster(t, conf)
+	tc.handle(NewEvent("", "onTime", "timer"))
+	tc.setReadyReplicas("web", 2)
+	tc.setReadyReplicas("web", 3)
+	if !assert.Len(t, tc.delayed, 1) {
+		return
+	}
+	assert.Equal(t, time.Minute, tc.delayed[0].duration)
+
+	tc.handle(tc.delayed[0].event)
+	assert.False(t, tc.IsRecovering())
+	assert.Equal(t, []string{"ReplicaSet/default/api"}, tc.LastFailedRecoveries())
+	// Another deschedule event is pushed, like after a recovery.
+	if assert.Len(t, tc.delayed, 2) {
+		assert.Equal(t, delayedEvent{NewEvent("", "onTime", "timer"), 5 * time.Second}, tc.delayed[1])
+	}
+
+	// The busy node is busy again by a pod of api, which failed to recover,
+	// the next term only evicts the pod of web.
+	tc.setReadyReplicas("api", 1)
+	obj, _, _ := tc.rsIndexer.GetByKey("default/api")
+	api := obj.(*apps_v1.ReplicaSet)
+	tc.clientset.PodIndexer.Add(fake.SetController(fake.NewPod("default", "api-3", "busy", "2", "1Gi"), "ReplicaSet", api))
+	tc.handle(NewEvent("", "onTime", "timer"))
+	evicted := tc.clientset.EvictedPods()
+	assert.Len(t, evicted, 3)
+	assert.NotContains(t, evicted, "default/api-3")
+}
+
+func TestDescheduleOutOfTime(t *testing.T) {
+	t.Parallel()
+	tc := newTestCluster(t, config.DefaultConfig())
+	tc.timer.outOfTime = true
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Empty(t, tc.clientset.EvictedPods())
+	assert.False(t, tc.IsRecovering())
+}
+
+func TestDescheduleDryRun(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.DryRun = true
+	tc := newTestCluster(t, conf)
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Empty(t, tc.clientset.EvictedPods())
+	// No workload lost a pod, the next dry run isn't held back by recovering.
+	assert.False(t, tc.IsRecovering())
+	assert.Len(t, tc.Status().LastEvictedPods, 2)
+	assert.Empty(t, tc.delayed)
+}
+
+func TestNewHandlerInvalidRecoverTimeout(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.RecoverTimeout = "soon"
+	_, err := NewHandler(conf, nil, &fakeTimer{}, nil)
+	assert.Error(t, err)
+}
+
+func TestConfigReload(t *testing.T) {
+	t.Parallel()
+	tc := newTestCluster(t, config.DefaultConfig())
+	conf := config.DefaultConfig()
+	conf.Rules.MaxEvictSize = 1
+	conf.Rules.RecoverTimeout = "0s"
+	tc.handle(NewConfigEvent(conf))
+
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Len(t, tc.clientset.EvictedPods(), 1)
+	assert.True(t, tc.IsRecovering())
+	// Recovering never times out with the reloaded config.
+	assert.Empty(t, tc.delayed)
+
+	// Config is swapped in while recovering as well.
+	conf.Rules.RecoverTimeout = "1m"
+	tc.handle(NewConfigEvent(conf))
+	assert.True(t, tc.IsRecovering())
+	assert.Equal(t, time.Minute, tc.recoverTimeout)
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	assert.NoError(t, ValidateConfig(conf))
+	conf.Rules.RecoverTimeout = "soon"
+	assert.Error(t, ValidateConfig(conf))
+}
+
+type statusRecorder []Status
+
+func (sr *statusRecorder) ReportStatus(status Status) {
+	*sr = append(*sr, status)
+}
+
+func (sr statusRecorder) states() []string {
+	var states []string
+	for _, s := range sr {
+		states = append(states, s.State)
+	}
+	return states
+}
+
+func TestPolicyStatus(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.RecoverTimeout = "1m"
+	tc := newTestCluster(t, conf)
+	tc.SetPolicy("a")
+	recorder := &statusRecorder{}
+	tc.SetStatusReporter(recorder)
+
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.Equal(t, []string{"descheduling", "recovering"}, recorder.states())
+	status := tc.Status()
+	assert.False(t, status.LastRunTime.IsZero())
+	assert.Len(t, status.LastEvictedPods, 2)
+
+	// The timeout is pushed for this policy only.
+	if !assert.Len(t, tc.delayed, 1) {
+		return
+	}
+	tc.handle(tc.delayed[0].event.ForPolicy("b"))
+	assert.True(t, tc.IsRecovering())
+	tc.handle(tc.delayed[0].event)
+	assert.False(t, tc.IsRecovering())
+	assert.Equal(t, []string{"descheduling", "recovering", "idle"}, recorder.states())
+	assert.Len(t, tc.Status().LastFailedRecoveries, 2)
+	// So is the next deschedule event, the other policies are not recovering from this term.
+	if assert.Len(t, tc.delayed, 2) {
+		assert.Equal(t, NewEvent("", "onTime", "timer").ForPolicy("a"), tc.delayed[1].event)
+	}
+}
+
+// One policy finishing recovery leaves the gauge of the others recovering.
+func TestRecoveringMetricByPolicy(t *testing.T) {
+	t.Parallel()
+	a := newTestCluster(t, config.DefaultConfig())
+	a.SetPolicy("recovering-a")
+	b := newTestCluster(t, config.DefaultConfig())
+	b.SetPolicy("recovering-b")
+
+	a.handle(NewEvent("", "onTime", "timer"))
+	b.handle(NewEvent("", "onTime", "timer"))
+	assert.Equal(t, 1.0, metrics.Recovering.Value("recovering-a"))
+	assert.Equal(t, 1.0, metrics.Recovering.Value("recovering-b"))
+
+	a.setReadyReplicas("web", 3)
+	a.setReadyReplicas("api", 2)
+	assert.False(t, a.IsRecovering())
+	assert.Equal(t, 0.0, metrics.Recovering.Value("recovering-a"))
+	assert.Equal(t, 1.0, metrics.Recovering.Value("recovering-b"))
+}
+
+// Events of the same object are merged by the workqueue, the term id is only
+// given when a deschedule term starts.
+func TestEventsOfSameObjectEqual(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, NewEvent("busy", "getReady", "node"), NewEvent("busy", "getReady", "node"))
+	assert.Equal(t, NewEvent("", "onTime", "timer").ForPolicy("a"), NewEvent("", "onTime", "timer").ForPolicy("a"))
+
+	tc := newTestCluster(t, config.DefaultConfig())
+	tc.handle(NewEvent("", "onTime", "timer"))
+	first := tc.termID
+	assert.NotEmpty(t, first)
+	tc.setReadyReplicas("web", 3)
+	tc.setReadyReplicas("api", 2)
+	tc.handle(NewEvent("", "onTime", "timer"))
+	assert.NotEqual(t, first, tc.termID)
+}
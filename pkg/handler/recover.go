@@ -1,29 +1,98 @@
 package handler
 
 import (
-	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
-	"github.com/lentil1016/descheduler/pkg/predictor"
-	"github.com/lentil1016/descheduler/pkg/timer"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
 )
 
 type recoverHandler struct {
+	h *Handler
 }
 
 func (rh *recoverHandler) Handle(event Event) {
-	rs := predictor.GetReplicaSetByKey(event.key)
-	if rs != nil {
-		if _, ok := recoveringMap[rs.ObjectMeta.Name]; ok {
-			delete(recoveringMap, rs.ObjectMeta.Name)
-			if len(recoveringMap) == 0 {
-				isRecovering = false
-				fmt.Println("recoverHandler: ReplicaSets that been evicted have now recovered")
-				fmt.Println("Push another schedule event after 5 second...")
-				timer.PushTimerEventAfter(5 * time.Second)
-			} else {
-				fmt.Printf("recoverHandler: Received ReplicaSet %v recover event. Still waiting for %v replica sets recovering\n", rs.ObjectMeta.Name, len(recoveringMap))
-			}
+	h := rh.h
+	if _, ok := h.recoveringMap[event.key]; !ok {
+		return
+	}
+	log := logger.WithValues("term", h.termID, "workload", event.key)
+	switch event.eventType {
+	case "update":
+		// Workload that is scaled down to its ready replicas is ready as well.
+		w := h.predictor.GetWorkloadByKey(event.key)
+		if w == nil || !w.IsReady() {
+			return
 		}
+		log.Info("Workload recovered")
+	case "delete":
+		log.Info("Workload deleted, stop waiting for it")
+	default:
+		return
+	}
+	delete(h.recoveringMap, event.key)
+	if len(h.recoveringMap) == 0 {
+		h.stopRecovering()
+		h.reportStatus()
+		log.Info("Workloads that been evicted have now recovered, push another schedule event after 5 seconds")
+		h.pushDescheduleEvent()
+	} else {
+		log.V(2).Info("Still waiting for workloads recovering", "recovering", len(h.recoveringMap))
+	}
+}
+
+type recoverTimeoutHandler struct {
+	h *Handler
+}
+
+func (rth *recoverTimeoutHandler) Handle(event Event) {
+	h := rth.h
+	if event.key != strconv.Itoa(h.recoveringTerm) {
+		// Timeout of a recovery that has already finished.
+		return
+	}
+	failed := make([]string, 0, len(h.recoveringMap))
+	for key := range h.recoveringMap {
+		failed = append(failed, key)
 	}
+	sort.Strings(failed)
+	h.lastFailedRecoveries = failed
+	// Evicting from them again would abandon the next recovery as well.
+	h.predictor.SetFailedWorkloads(failed)
+	metrics.RecoveriesAbandoned.Inc()
+	metrics.FailedRecoveries.Add(float64(len(failed)))
+	logger.Info("Recovery abandoned, push another deschedule event after 5 seconds",
+		"term", h.termID, "timeout", h.recoverTimeout, "failed", failed)
+	h.stopRecovering()
+	h.reportStatus()
+	// A workload stuck recovering must not take the rest of the time window,
+	// the event is ignored once the window is over.
+	h.pushDescheduleEvent()
+}
+
+// pushDescheduleEvent pushes the timer event starting the next term after 5
+// seconds, for this handler only, the other policies are not recovering from it.
+func (h *Handler) pushDescheduleEvent() {
+	h.pushEventAfter(NewEvent("", "onTime", "timer").ForPolicy(h.policy), 5*time.Second)
+}
+
+// startRecovering turns the handler into recovering state, until every
+// workload in recoveringMap recovered or the recovery timed out.
+func (h *Handler) startRecovering() {
+	h.isRecovering = true
+	h.recoveringTerm++
+	h.recoveringSince = time.Now()
+	metrics.Recovering.Set(1, h.policy)
+	if h.recoverTimeout > 0 {
+		h.pushEventAfter(NewEvent(strconv.Itoa(h.recoveringTerm), "timeout", "recovery").ForPolicy(h.policy), h.recoverTimeout)
+	}
+}
+
+func (h *Handler) stopRecovering() {
+	h.isRecovering = false
+	h.recoveringMap = nil
+	metrics.Recovering.Set(0, h.policy)
+	metrics.RecoveringSeconds.Add(time.Since(h.recoveringSince).Seconds())
 }
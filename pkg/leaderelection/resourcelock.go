@@ -0,0 +1,239 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	coordination_v1 "k8s.io/api/coordination/v1"
+	api_v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	LeaderElectionRecordAnnotationKey = "control-plane.alpha.kubernetes.io/leader"
+	LeasesResourceLock                = "leases"
+	ConfigMapsResourceLock            = "configmaps"
+)
+
+// LeaderElectionRecord is the record that is stored in the leader election annotation.
+// This information should be used for observational purposes only and could be replaced
+// with a random string (e.g. UUID) with only slight modification of this code.
+type LeaderElectionRecord struct {
+	HolderIdentity       string      `json:"holderIdentity"`
+	LeaseDurationSeconds int         `json:"leaseDurationSeconds"`
+	AcquireTime          metav1.Time `json:"acquireTime"`
+	RenewTime            metav1.Time `json:"renewTime"`
+	LeaderTransitions    int         `json:"leaderTransitions"`
+}
+
+// Interface offers a common interface for locking on arbitrary
+// resources used in leader election.
+type Interface interface {
+	// Get returns the LeaderElectionRecord
+	Get() (*LeaderElectionRecord, error)
+
+	// Create attempts to create a LeaderElectionRecord
+	Create(ler LeaderElectionRecord) error
+
+	// Update will update and existing LeaderElectionRecord
+	Update(ler LeaderElectionRecord) error
+
+	// Identity will return the locks Identity
+	Identity() string
+
+	// Describe is used to convert details on current resource lock
+	// into a string
+	Describe() string
+}
+
+// NewResourceLock creates a lock of the given type, either leases or configmaps.
+func NewResourceLock(lockType, ns, name string, client kubernetes.Interface, identity string) (Interface, error) {
+	switch lockType {
+	case LeasesResourceLock:
+		return &leaseLock{namespace: ns, name: name, client: client, identity: identity}, nil
+	case ConfigMapsResourceLock:
+		return &configMapLock{namespace: ns, name: name, client: client, identity: identity}, nil
+	default:
+		return nil, fmt.Errorf("Invalid lock-type %s, either set it to [%s] or [%s]", lockType, LeasesResourceLock, ConfigMapsResourceLock)
+	}
+}
+
+type leaseLock struct {
+	namespace string
+	name      string
+	client    kubernetes.Interface
+	identity  string
+	lease     *coordination_v1.Lease
+}
+
+// Get returns the election record from a Lease spec
+func (ll *leaseLock) Get() (*LeaderElectionRecord, error) {
+	var err error
+	ll.lease, err = ll.client.CoordinationV1().Leases(ll.namespace).Get(ll.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return leaseSpecToLeaderElectionRecord(&ll.lease.Spec), nil
+}
+
+// Create attempts to create a Lease
+func (ll *leaseLock) Create(ler LeaderElectionRecord) error {
+	var err error
+	ll.lease, err = ll.client.CoordinationV1().Leases(ll.namespace).Create(&coordination_v1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ll.name,
+			Namespace: ll.namespace,
+		},
+		Spec: leaderElectionRecordToLeaseSpec(&ler),
+	})
+	return err
+}
+
+// Update will update an existing Lease spec.
+func (ll *leaseLock) Update(ler LeaderElectionRecord) error {
+	if ll.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+	ll.lease.Spec = leaderElectionRecordToLeaseSpec(&ler)
+	var err error
+	ll.lease, err = ll.client.CoordinationV1().Leases(ll.namespace).Update(ll.lease)
+	return err
+}
+
+// Describe is used to convert details on current resource lock
+// into a string
+func (ll *leaseLock) Describe() string {
+	return fmt.Sprintf("%v/%v", ll.namespace, ll.name)
+}
+
+// Identity returns the Identity of the lock
+func (ll *leaseLock) Identity() string {
+	return ll.identity
+}
+
+func leaseSpecToLeaderElectionRecord(spec *coordination_v1.LeaseSpec) *LeaderElectionRecord {
+	holderIdentity := ""
+	if spec.HolderIdentity != nil {
+		holderIdentity = *spec.HolderIdentity
+	}
+	leaseDurationSeconds := 0
+	if spec.LeaseDurationSeconds != nil {
+		leaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	leaseTransitions := 0
+	if spec.LeaseTransitions != nil {
+		leaseTransitions = int(*spec.LeaseTransitions)
+	}
+	record := &LeaderElectionRecord{
+		HolderIdentity:       holderIdentity,
+		LeaseDurationSeconds: leaseDurationSeconds,
+		LeaderTransitions:    leaseTransitions,
+	}
+	if spec.AcquireTime != nil {
+		record.AcquireTime = metav1.Time{Time: spec.AcquireTime.Time}
+	}
+	if spec.RenewTime != nil {
+		record.RenewTime = metav1.Time{Time: spec.RenewTime.Time}
+	}
+	return record
+}
+
+func leaderElectionRecordToLeaseSpec(ler *LeaderElectionRecord) coordination_v1.LeaseSpec {
+	leaseDurationSeconds := int32(ler.LeaseDurationSeconds)
+	leaseTransitions := int32(ler.LeaderTransitions)
+	return coordination_v1.LeaseSpec{
+		HolderIdentity:       &ler.HolderIdentity,
+		LeaseDurationSeconds: &leaseDurationSeconds,
+		AcquireTime:          &metav1.MicroTime{Time: ler.AcquireTime.Time},
+		RenewTime:            &metav1.MicroTime{Time: ler.RenewTime.Time},
+		LeaseTransitions:     &leaseTransitions,
+	}
+}
+
+type configMapLock struct {
+	namespace string
+	name      string
+	client    kubernetes.Interface
+	identity  string
+	cm        *api_v1.ConfigMap
+}
+
+// Get returns the election record from a ConfigMap Annotation
+func (cml *configMapLock) Get() (*LeaderElectionRecord, error) {
+	var record LeaderElectionRecord
+	var err error
+	cml.cm, err = cml.client.CoreV1().ConfigMaps(cml.namespace).Get(cml.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if cml.cm.Annotations == nil {
+		cml.cm.Annotations = make(map[string]string)
+	}
+	if recordBytes, found := cml.cm.Annotations[LeaderElectionRecordAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(recordBytes), &record); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+// Create attempts to create a LeaderElectionRecord annotation
+func (cml *configMapLock) Create(ler LeaderElectionRecord) error {
+	recordBytes, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	cml.cm, err = cml.client.CoreV1().ConfigMaps(cml.namespace).Create(&api_v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cml.name,
+			Namespace: cml.namespace,
+			Annotations: map[string]string{
+				LeaderElectionRecordAnnotationKey: string(recordBytes),
+			},
+		},
+	})
+	return err
+}
+
+// Update will update an existing annotation on a given resource.
+func (cml *configMapLock) Update(ler LeaderElectionRecord) error {
+	if cml.cm == nil {
+		return errors.New("configmap not initialized, call get or create first")
+	}
+	recordBytes, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	cml.cm.Annotations[LeaderElectionRecordAnnotationKey] = string(recordBytes)
+	cml.cm, err = cml.client.CoreV1().ConfigMaps(cml.namespace).Update(cml.cm)
+	return err
+}
+
+// Describe is used to convert details on current resource lock
+// into a string
+func (cml *configMapLock) Describe() string {
+	return fmt.Sprintf("%v/%v", cml.namespace, cml.name)
+}
+
+// Identity returns the Identity of the lock
+func (cml *configMapLock) Identity() string {
+	return cml.identity
+}
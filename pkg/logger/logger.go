@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// Logger writes leveled logs with key/value pairs. Text logs are written
+// through klog, json logs are written to stderr as one object per line.
+// Verbosity of both formats is controlled by klog's -v flag.
+type Logger struct {
+	values []interface{}
+}
+
+// Verbose writes info logs only if the verbosity is at least its level.
+type Verbose struct {
+	logger  Logger
+	level   int
+	enabled bool
+}
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+var format = TextFormat
+var jsonMutex sync.Mutex
+var root Logger
+
+// SetFormat sets the format of all the logs, either text or json.
+func SetFormat(logFormat string) error {
+	if logFormat != TextFormat && logFormat != JSONFormat {
+		return fmt.Errorf("Can't recognize log format %v, either set it to [%v] or [%v]", logFormat, TextFormat, JSONFormat)
+	}
+	format = logFormat
+	return nil
+}
+
+// WithValues returns a logger adding the key/value pairs to every log it writes.
+func WithValues(keysAndValues ...interface{}) Logger {
+	return root.WithValues(keysAndValues...)
+}
+
+// Info writes an info log with the key/value pairs.
+func Info(msg string, keysAndValues ...interface{}) {
+	root.write("info", 0, msg, keysAndValues)
+}
+
+// Error writes an error log with the key/value pairs.
+func Error(err error, msg string, keysAndValues ...interface{}) {
+	root.write("error", 0, msg, append([]interface{}{"error", err}, keysAndValues...))
+}
+
+// V returns a Verbose writing info logs only if the verbosity is at least level.
+func V(level int) Verbose {
+	return root.V(level)
+}
+
+func (l Logger) WithValues(keysAndValues ...interface{}) Logger {
+	values := make([]interface{}, 0, len(l.values)+len(keysAndValues))
+	values = append(values, l.values...)
+	values = append(values, keysAndValues...)
+	return Logger{values: values}
+}
+
+func (l Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.write("info", 0, msg, keysAndValues)
+}
+
+func (l Logger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.write("error", 0, msg, append([]interface{}{"error", err}, keysAndValues...))
+}
+
+func (l Logger) V(level int) Verbose {
+	return Verbose{
+		logger:  l,
+		level:   level,
+		enabled: bool(klog.V(klog.Level(level))),
+	}
+}
+
+func (v Verbose) Info(msg string, keysAndValues ...interface{}) {
+	if v.enabled {
+		v.logger.write("info", v.level, msg, keysAndValues)
+	}
+}
+
+func (l Logger) write(severity string, level int, msg string, keysAndValues []interface{}) {
+	values := append(append([]interface{}{}, l.values...), keysAndValues...)
+	if len(values)%2 != 0 {
+		values = append(values, "(MISSING)")
+	}
+	if format == JSONFormat {
+		writeJSON(severity, level, msg, values)
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(msg)
+	for i := 0; i < len(values); i += 2 {
+		fmt.Fprintf(&buf, " %v=%v", values[i], formatValue(values[i+1]))
+	}
+	// Depth 2 points klog's file:line header to the caller of the logger.
+	if severity == "error" {
+		klog.ErrorDepth(2, buf.String())
+	} else {
+		klog.InfoDepth(2, buf.String())
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case error:
+		return strconv.Quote(v.Error())
+	case fmt.Stringer:
+		return strconv.Quote(v.String())
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func writeJSON(severity string, level int, msg string, values []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	writeJSONField(&buf, "ts", time.Now().Format(time.RFC3339Nano))
+	buf.WriteString(",")
+	writeJSONField(&buf, "level", severity)
+	buf.WriteString(",")
+	writeJSONField(&buf, "v", level)
+	buf.WriteString(",")
+	writeJSONField(&buf, "msg", msg)
+	for i := 0; i < len(values); i += 2 {
+		buf.WriteString(",")
+		value := values[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		writeJSONField(&buf, fmt.Sprint(values[i]), value)
+	}
+	buf.WriteString("}\n")
+
+	jsonMutex.Lock()
+	defer jsonMutex.Unlock()
+	os.Stderr.Write(buf.Bytes())
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	keyBytes, _ := json.Marshal(key)
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		valueBytes, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf.Write(keyBytes)
+	buf.WriteString(":")
+	buf.Write(valueBytes)
+}
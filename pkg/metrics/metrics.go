@@ -1 +1,157 @@
 package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The metrics are exposed in the Prometheus text format. The prometheus client
+// library is not vendored to avoid the huge vendoring issues, the few metric
+// types descheduler needs are implemented here instead.
+
+// Values of the result label of Evictions.
+const (
+	EvictionSuccess    = "success"
+	EvictionPDBBlocked = "pdb_blocked"
+	EvictionNotFound   = "not_found"
+	EvictionError      = "error"
+)
+
+var (
+	// DescheduleTerms counts the deschedule terms, labelled by the resource type of the triggering event.
+	DescheduleTerms = newMetric("descheduler_deschedule_terms_total", "Number of deschedule terms triggered.", "counter", "trigger")
+	// SelectedPods counts the pods marked as evicted, labelled by the strategy that marked them.
+	SelectedPods = newMetric("descheduler_selected_pods_total", "Number of pods selected to be evicted.", "counter", "strategy")
+	// DroppedPods counts the selected pods that are not evicted, labelled by the reason they are dropped.
+	DroppedPods = newMetric("descheduler_dropped_pods_total", "Number of selected pods dropped from eviction.", "counter", "reason")
+	// Evictions counts the eviction requests, labelled by their result.
+	Evictions = newMetric("descheduler_evictions_total", "Number of pod evictions executed.", "counter", "result")
+	// Nodes is the number of nodes in each classification of the latest deschedule term.
+	Nodes = newMetric("descheduler_nodes", "Number of nodes classified as usage, spared or normal in the latest deschedule term.", "gauge", "classification")
+	// ExcludedNodes is the number of nodes excluded from the latest deschedule term, labelled by the reason.
+	ExcludedNodes = newMetric("descheduler_excluded_nodes", "Number of nodes excluded from the latest deschedule term.", "gauge", "reason")
+	// Recovering is 1 while descheduler is waiting for evicted workloads to recover,
+	// by the DeschedulerPolicy waiting, empty without policies.
+	Recovering = newMetric("descheduler_recovering", "Whether descheduler is waiting for evicted workloads to recover.", "gauge", "policy")
+	// RecoveringSeconds accumulates the time spent waiting for evicted workloads to recover.
+	RecoveringSeconds = newMetric("descheduler_recovering_seconds_total", "Time spent waiting for evicted workloads to recover.", "counter")
+	// RecoveriesAbandoned counts the recoveries abandoned because of spec.rules.recoverTimeout.
+	RecoveriesAbandoned = newMetric("descheduler_recoveries_abandoned_total", "Number of recoveries abandoned after timing out.", "counter")
+	// FailedRecoveries counts the workloads that were still recovering when their recovery got abandoned.
+	FailedRecoveries = newMetric("descheduler_failed_recoveries_total", "Number of workloads that failed to recover before the recovery timed out.", "counter")
+)
+
+var registry []*Metric
+
+// labelValueEscaper escapes the label values as the Prometheus text format
+// requires, which is not what %q does to non-ASCII or control characters.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metric is a counter or a gauge with an optional set of labels.
+type Metric struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+
+	mutex  sync.Mutex
+	values map[string]float64
+	labels map[string][]string
+}
+
+func newMetric(name, help, metricType string, labelNames ...string) *Metric {
+	m := &Metric{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		values:     map[string]float64{},
+		labels:     map[string][]string{},
+	}
+	registry = append(registry, m)
+	return m
+}
+
+// Inc increases the value with the given label values by 1.
+func (m *Metric) Inc(labelValues ...string) {
+	m.Add(1, labelValues...)
+}
+
+// Add increases the value with the given label values by v.
+func (m *Metric) Add(v float64, labelValues ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := m.key(labelValues)
+	m.values[key] += v
+}
+
+// Set sets the value with the given label values to v.
+func (m *Metric) Set(v float64, labelValues ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := m.key(labelValues)
+	m.values[key] = v
+}
+
+// Value returns the value with the given label values.
+func (m *Metric) Value(labelValues ...string) float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.values[m.key(labelValues)]
+}
+
+// key must be called with the mutex held.
+func (m *Metric) key(labelValues []string) string {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metric %v expects %v label values, got %v", m.name, len(m.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	if _, ok := m.labels[key]; !ok {
+		m.labels[key] = append([]string{}, labelValues...)
+	}
+	return key
+}
+
+func (m *Metric) write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	fmt.Fprintf(w, "# HELP %v %v\n", m.name, m.help)
+	fmt.Fprintf(w, "# TYPE %v %v\n", m.name, m.metricType)
+	if len(m.labelNames) == 0 {
+		fmt.Fprintf(w, "%v %v\n", m.name, m.values[""])
+		return
+	}
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pairs := make([]string, 0, len(m.labelNames))
+		for i, value := range m.labels[key] {
+			pairs = append(pairs, fmt.Sprintf(`%v="%v"`, m.labelNames[i], labelValueEscaper.Replace(value)))
+		}
+		fmt.Fprintf(w, "%v{%v} %v\n", m.name, strings.Join(pairs, ","), m.values[key])
+	}
+}
+
+// Handler returns a http handler that writes all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, m := range registry {
+			m.write(w)
+		}
+	})
+}
+
+// Serve serves the metrics on the /metrics path of addr, it blocks until the server fails.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	gauge := newMetric("test_gauge", "A labelled gauge.", "gauge", "policy", "node")
+	gauge.Set(1, "a", "b")
+	gauge.Set(0.5, `quote"d \back`+"\nline", "é")
+	counter := newMetric("test_total", "A counter.", "counter")
+	counter.Inc()
+	counter.Add(2)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "# HELP test_gauge A labelled gauge.\n"+
+		"# TYPE test_gauge gauge\n"+
+		`test_gauge{policy="a",node="b"} 1`+"\n"+
+		// Only backslash, double quote and line feed are escaped, the rest is UTF-8 as is.
+		`test_gauge{policy="quote\"d \\back\nline",node="é"} 0.5`+"\n")
+	assert.Contains(t, body, "# HELP test_total A counter.\n"+
+		"# TYPE test_total counter\n"+
+		"test_total 3\n")
+}
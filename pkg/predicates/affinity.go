@@ -0,0 +1,94 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The following code is adapted from the inter pod affinity predicate in
+// k8s.io/kubernetes/pkg/scheduler/algorithm/predicates, it checks a pair of
+// pods that are already placed instead of a pod against the whole cluster.
+
+// PodsViolateAntiAffinity checks if pod on node and other on otherNode violate
+// the required pod anti-affinity of either of them.
+func PodsViolateAntiAffinity(pod *v1.Pod, node *v1.Node, other *v1.Pod, otherNode *v1.Node) bool {
+	return podMatchesAntiAffinityTerms(pod, node, other, otherNode) ||
+		podMatchesAntiAffinityTerms(other, otherNode, pod, node)
+}
+
+// podMatchesAntiAffinityTerms checks if other on otherNode matches any of the
+// required anti-affinity terms of pod on node.
+func podMatchesAntiAffinityTerms(pod *v1.Pod, node *v1.Node, other *v1.Pod, otherNode *v1.Node) bool {
+	for _, term := range getPodAntiAffinityTerms(pod.Spec.Affinity) {
+		if !nodesHaveSameTopologyKey(node, otherNode, term.TopologyKey) {
+			continue
+		}
+		namespaces := term.Namespaces
+		if len(namespaces) == 0 {
+			// An empty list means the namespace of the pod with the term.
+			namespaces = []string{pod.Namespace}
+		}
+		if !containsString(namespaces, other.Namespace) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(other.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPodAntiAffinityTerms checks if the pod has any required pod anti-affinity term.
+func HasPodAntiAffinityTerms(pod *v1.Pod) bool {
+	return len(getPodAntiAffinityTerms(pod.Spec.Affinity)) > 0
+}
+
+func getPodAntiAffinityTerms(affinity *v1.Affinity) []v1.PodAffinityTerm {
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	return affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+}
+
+// nodesHaveSameTopologyKey checks if both nodes have the topology key label with the same value.
+func nodesHaveSameTopologyKey(nodeA, nodeB *v1.Node, topologyKey string) bool {
+	if nodeA == nil || nodeB == nil || len(topologyKey) == 0 {
+		return false
+	}
+	valueA, ok := nodeA.Labels[topologyKey]
+	if !ok {
+		return false
+	}
+	valueB, ok := nodeB.Labels[topologyKey]
+	return ok && valueA == valueB
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
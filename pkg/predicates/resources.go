@@ -0,0 +1,166 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"k8s.io/api/core/v1"
+	v1_resource "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+// The following code is adapted from the resource fit and host port predicates
+// in k8s.io/kubernetes/pkg/scheduler/algorithm/predicates, and from NodeInfo in
+// k8s.io/kubernetes/pkg/scheduler/cache.
+
+// NodeInfo is a node and the resources taken by the pods on it.
+type NodeInfo struct {
+	Node *v1.Node
+	// RequestedMilliCPU and RequestedMemory are the sums of the requests of the pods.
+	RequestedMilliCPU int64
+	RequestedMemory   int64
+	// RequestedScalars are the sums of the requests of every other resource, like
+	// ephemeral-storage, hugepages-2Mi or nvidia.com/gpu.
+	RequestedScalars map[v1.ResourceName]int64
+	Pods             int
+	usedPorts        []v1.ContainerPort
+}
+
+// NewNodeInfo creates the NodeInfo of the node running the pods.
+func NewNodeInfo(node *v1.Node, pods ...*v1.Pod) *NodeInfo {
+	info := &NodeInfo{Node: node, RequestedScalars: map[v1.ResourceName]int64{}}
+	for _, pod := range pods {
+		info.AddPod(pod)
+	}
+	return info
+}
+
+// AddPod takes the resources of the pod on the node.
+func (n *NodeInfo) AddPod(pod *v1.Pod) {
+	milliCPU, memory, scalars := getPodRequest(pod)
+	n.RequestedMilliCPU += milliCPU
+	n.RequestedMemory += memory
+	for name, value := range scalars {
+		n.RequestedScalars[name] += value
+	}
+	n.Pods++
+	n.usedPorts = append(n.usedPorts, getHostPorts(pod)...)
+}
+
+// Allocatable returns the cpu in millicores, the memory in bytes, and the
+// number of pods that the node can take.
+func (n *NodeInfo) Allocatable() (int64, int64, int64) {
+	allocatable := n.Node.Status.Allocatable
+	if len(allocatable) == 0 {
+		allocatable = n.Node.Status.Capacity
+	}
+	return allocatable.Cpu().MilliValue(), allocatable.Memory().Value(), allocatable.Pods().Value()
+}
+
+// allocatableScalar returns the amount of the resource that the node can take,
+// zero if the node doesn't have it.
+func (n *NodeInfo) allocatableScalar(name v1.ResourceName) int64 {
+	allocatable := n.Node.Status.Allocatable
+	if len(allocatable) == 0 {
+		allocatable = n.Node.Status.Capacity
+	}
+	quantity := allocatable[name]
+	return quantity.Value()
+}
+
+// PodFitsResources checks if the node has enough cpu, memory, pod slots and
+// every other resource the pod requests left for the pod. A node that doesn't
+// have a resource the pod requests never fits it.
+func PodFitsResources(pod *v1.Pod, nodeInfo *NodeInfo) bool {
+	allocatableMilliCPU, allocatableMemory, allowedPods := nodeInfo.Allocatable()
+	if int64(nodeInfo.Pods)+1 > allowedPods {
+		return false
+	}
+	milliCPU, memory, scalars := getPodRequest(pod)
+	if milliCPU == 0 && memory == 0 && len(scalars) == 0 {
+		return true
+	}
+	if nodeInfo.RequestedMilliCPU+milliCPU > allocatableMilliCPU ||
+		nodeInfo.RequestedMemory+memory > allocatableMemory {
+		return false
+	}
+	for name, value := range scalars {
+		if nodeInfo.RequestedScalars[name]+value > nodeInfo.allocatableScalar(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// PodFitsHostPorts checks if none of the host ports the pod asks for is taken on the node.
+func PodFitsHostPorts(pod *v1.Pod, nodeInfo *NodeInfo) bool {
+	for _, wanted := range getHostPorts(pod) {
+		for _, used := range nodeInfo.usedPorts {
+			if portsConflict(wanted, used) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getPodRequest returns the cpu in millicores, the memory in bytes, and the
+// non-zero requests of every other resource of the pod.
+func getPodRequest(pod *v1.Pod) (int64, int64, map[v1.ResourceName]int64) {
+	requests, _ := v1_resource.PodRequestsAndLimits(pod)
+	scalars := map[v1.ResourceName]int64{}
+	for name, quantity := range requests {
+		if name == v1.ResourceCPU || name == v1.ResourceMemory || quantity.IsZero() {
+			continue
+		}
+		scalars[name] = quantity.Value()
+	}
+	return requests.Cpu().MilliValue(), requests.Memory().Value(), scalars
+}
+
+func getHostPorts(pod *v1.Pod) []v1.ContainerPort {
+	var ports []v1.ContainerPort
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.HostPort > 0 {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// portsConflict checks if both ports take the same host port of the same
+// protocol, on the same host IP or on all of them.
+func portsConflict(a, b v1.ContainerPort) bool {
+	if a.HostPort != b.HostPort || protocol(a) != protocol(b) {
+		return false
+	}
+	return hostIP(a) == hostIP(b) || hostIP(a) == "0.0.0.0" || hostIP(b) == "0.0.0.0"
+}
+
+func protocol(port v1.ContainerPort) v1.Protocol {
+	if port.Protocol == "" {
+		return v1.ProtocolTCP
+	}
+	return port.Protocol
+}
+
+func hostIP(port v1.ContainerPort) string {
+	if port.HostIP == "" {
+		return "0.0.0.0"
+	}
+	return port.HostIP
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"k8s.io/api/core/v1"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+// The following code is adapted from the taint toleration predicate in
+// k8s.io/kubernetes/pkg/scheduler/algorithm/predicates.
+
+// PodToleratesNodeTaints checks if the pod tolerates the taints of the node
+// that filter returns true for.
+func PodToleratesNodeTaints(pod *v1.Pod, node *v1.Node, filter func(t *v1.Taint) bool) bool {
+	if node == nil {
+		return false
+	}
+	return v1helper.TolerationsTolerateTaintsWithFilter(pod.Spec.Tolerations, node.Spec.Taints, filter)
+}
+
+// IsSchedulingTaint tells the taints the scheduler keeps pods away from the node for.
+func IsSchedulingTaint(t *v1.Taint) bool {
+	return t.Effect == v1.TaintEffectNoSchedule || t.Effect == v1.TaintEffectNoExecute
+}
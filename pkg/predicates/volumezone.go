@@ -0,0 +1,56 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package predicates
+
+import (
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// The following code is adapted from the volume zone predicate in
+// k8s.io/kubernetes/pkg/scheduler/algorithm/predicates.
+
+// PodFitsVolumeZone checks if the node is in the zones and regions of the
+// persistent volumes the pod uses. A node without zone and region labels fits.
+func PodFitsVolumeZone(node *v1.Node, volumes []*v1.PersistentVolume) bool {
+	if node == nil {
+		return false
+	}
+	nodeConstraints := make(map[string]string)
+	for _, key := range []string{v1.LabelZoneFailureDomain, v1.LabelZoneRegion} {
+		if value, ok := node.Labels[key]; ok {
+			nodeConstraints[key] = value
+		}
+	}
+	if len(nodeConstraints) == 0 {
+		return true
+	}
+	for _, pv := range volumes {
+		for key, value := range pv.Labels {
+			nodeValue, ok := nodeConstraints[key]
+			if !ok {
+				continue
+			}
+			// A volume spanning zones has them joined by "__".
+			if !containsString(strings.Split(value, "__"), nodeValue) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/predicates"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// placedPod is a pod running in the cluster and the node it runs on.
+type placedPod struct {
+	pod  *api_v1.Pod
+	node *api_v1.Node
+}
+
+// evictAntiAffinityViolations marks the pods violating required pod anti-affinity,
+// either their own or the one of the pods around them. The pods that resolve the
+// most violations are marked first, so that the fewest pods are evicted, and only
+// if another node is found for them where they violate nothing.
+func (p *Predictor) evictAntiAffinityViolations(log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
+	placed, withTerms := p.getPlacedPods(log)
+	if len(withTerms) == 0 {
+		// Nothing can be violated.
+		return pods, nil
+	}
+
+	// violations of every pod, keyed by the pods violated with.
+	violations := make(map[k8stypes.UID]map[k8stypes.UID]bool, len(pods))
+	for _, pod := range pods {
+		node, err := p.getPodNode(pod)
+		if err != nil {
+			continue
+		}
+		others := withTerms
+		if predicates.HasPodAntiAffinityTerms(pod) {
+			others = placed
+		}
+		for _, other := range others {
+			if other.pod.UID == pod.UID {
+				continue
+			}
+			if predicates.PodsViolateAntiAffinity(pod, node, other.pod, other.node) {
+				if violations[pod.UID] == nil {
+					violations[pod.UID] = make(map[k8stypes.UID]bool)
+				}
+				violations[pod.UID][other.pod.UID] = true
+			}
+		}
+	}
+
+	evicted := make(map[k8stypes.UID]bool)
+	unfit := make(map[k8stypes.UID]bool)
+	for {
+		// Pick the pod with the most unresolved violations, the first one ranks higher in a tie.
+		var picked *api_v1.Pod
+		most := 0
+		for _, pod := range pods {
+			if evicted[pod.UID] || unfit[pod.UID] {
+				continue
+			}
+			count := 0
+			for other := range violations[pod.UID] {
+				if !evicted[other] {
+					count++
+				}
+			}
+			if count > most {
+				picked, most = pod, count
+			}
+		}
+		if picked == nil {
+			break
+		}
+		if !p.podFitsAnyNodeWithoutViolation(log, picked, placed, evicted) {
+			log.V(4).Info("No other node without anti-affinity violation for pod", "pod", picked.Name)
+			unfit[picked.UID] = true
+			continue
+		}
+		log.V(2).Info("Pod anti-affinity violated, pod marked as evicted", "pod", picked.Name, "violations", most)
+		evicted[picked.UID] = true
+	}
+
+	var remains, evicts []*api_v1.Pod
+	for _, pod := range pods {
+		if evicted[pod.UID] {
+			evicts = append(evicts, pod)
+		} else {
+			remains = append(remains, pod)
+		}
+	}
+	return remains, evicts
+}
+
+// getPlacedPods returns the pods running on nodes, and those of them having
+// required pod anti-affinity.
+func (p *Predictor) getPlacedPods(log logger.Logger) (placed, withTerms []placedPod) {
+	nodes, err := p.nodeLister.List(labels.Everything())
+	if err != nil {
+		log.Error(err, "List nodes failed")
+		return nil, nil
+	}
+	nodesByName := make(map[string]*api_v1.Node, len(nodes))
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+	for _, obj := range p.indexers.PodIndexer.List() {
+		pod := obj.(*api_v1.Pod)
+		node, ok := nodesByName[pod.Spec.NodeName]
+		if !ok || pod.DeletionTimestamp != nil ||
+			pod.Status.Phase == api_v1.PodSucceeded || pod.Status.Phase == api_v1.PodFailed {
+			continue
+		}
+		placed = append(placed, placedPod{pod, node})
+		if predicates.HasPodAntiAffinityTerms(pod) {
+			withTerms = append(withTerms, placedPod{pod, node})
+		}
+	}
+	return placed, withTerms
+}
+
+// podFitsAnyNodeWithoutViolation checks if there is another schedulable node the
+// pod prefers, where it violates no anti-affinity with the pods not evicted.
+func (p *Predictor) podFitsAnyNodeWithoutViolation(log logger.Logger, pod *api_v1.Pod, placed []placedPod, evicted map[k8stypes.UID]bool) bool {
+	nodes, err := p.getOperatableNodes()
+	if err != nil {
+		log.Error(err, "Get operatable nodes failed")
+		return false
+	}
+	for _, node := range nodes {
+		if node.Name == pod.Spec.NodeName || !isNodeSchedulable(node) {
+			continue
+		}
+		if ok, err := predicates.PodMatchNodeSelector(pod, node); err != nil || !ok {
+			continue
+		}
+		violated := false
+		for _, other := range placed {
+			if other.pod.UID == pod.UID || evicted[other.pod.UID] {
+				continue
+			}
+			if predicates.PodsViolateAntiAffinity(pod, node, other.pod, other.node) {
+				violated = true
+				break
+			}
+		}
+		if !violated {
+			log.V(4).Info("Pod can possibly be scheduled on another node without violation", "pod", pod.Name, "target", node.Name)
+			return true
+		}
+	}
+	return false
+}
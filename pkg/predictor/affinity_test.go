@@ -0,0 +1,65 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+const hostname = "kubernetes.io/hostname"
+
+func TestEvictAntiAffinityViolations(t *testing.T) {
+	t.Parallel()
+	db := fake.NewReplicaSet("default", "db", 3)
+	web := fake.NewReplicaSet("default", "web", 2)
+	newDB := func(name, node string) *api_v1.Pod {
+		return fake.SetAntiAffinity(fake.SetController(fake.NewPod("default", name, node, "100m", "1Gi"), "ReplicaSet", db), "db", hostname)
+	}
+	db1, db2, db3 := newDB("db-1", "a"), newDB("db-2", "a"), newDB("db-3", "b")
+	web1 := fake.SetController(fake.NewPod("default", "web-1", "a", "100m", "1Gi"), "ReplicaSet", web)
+	// lonely refuses to run with web, it can't be evicted itself.
+	lonely := fake.SetAntiAffinity(fake.NewPod("default", "lonely", "a", "100m", "1Gi"), "web", hostname)
+
+	tests := []struct {
+		name       string
+		objs       []interface{}
+		candidates []*api_v1.Pod
+		evicted    []string
+	}{
+		{
+			name:       "one of the violating pair",
+			objs:       []interface{}{fake.NewNode("c", "4", "8Gi", 110), db1, db2, db3},
+			candidates: []*api_v1.Pod{db1, db2},
+			evicted:    []string{"db-1"},
+		},
+		{
+			name:       "no node without violation",
+			objs:       []interface{}{db1, db2, db3},
+			candidates: []*api_v1.Pod{db1, db2},
+			evicted:    []string{},
+		},
+		{
+			name:       "violating anti-affinity of another pod",
+			objs:       []interface{}{fake.NewNode("c", "4", "8Gi", 110), web1, lonely},
+			candidates: []*api_v1.Pod{web1},
+			evicted:    []string{"web-1"},
+		},
+		{
+			name:       "no violation",
+			objs:       []interface{}{fake.NewNode("c", "4", "8Gi", 110), db1, db3, web1},
+			candidates: []*api_v1.Pod{db1, web1},
+			evicted:    []string{},
+		},
+	}
+	for _, test := range tests {
+		objs := append([]interface{}{fake.NewNode("a", "4", "8Gi", 110), fake.NewNode("b", "4", "8Gi", 110)}, test.objs...)
+		p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+		remains, evicts := p.evictAntiAffinityViolations(logger.WithValues("test", test.name), test.candidates)
+		assert.Equal(t, test.evicted, podNames(evicts), test.name)
+		assert.Len(t, remains, len(test.candidates)-len(evicts), test.name)
+	}
+}
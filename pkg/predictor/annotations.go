@@ -0,0 +1,52 @@
+package predictor
+
+import (
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// EvictAnnotationKey opts the pods out of eviction when it's set to "false", and
+// opts the pods with local storage in when it's set to "true". It's read from
+// pods, and from their namespaces when the pods don't have it.
+const EvictAnnotationKey = "descheduler.lentil1016.cn/evict"
+
+// MinAgeAnnotationKey is the duration pods must have been running for before they
+// can be evicted. It's read from pods, and from their namespaces as well.
+const MinAgeAnnotationKey = "descheduler.lentil1016.cn/min-age"
+
+// getPodAnnotation returns the annotation of the pod, or of its namespace if the pod doesn't have it.
+func (p *Predictor) getPodAnnotation(pod *api_v1.Pod, key string) (string, bool) {
+	if value, ok := pod.Annotations[key]; ok {
+		return value, true
+	}
+	if p.indexers.NamespaceIndexer == nil {
+		return "", false
+	}
+	obj, ok, err := p.indexers.NamespaceIndexer.GetByKey(pod.Namespace)
+	if err != nil || !ok {
+		return "", false
+	}
+	value, ok := obj.(*api_v1.Namespace).Annotations[key]
+	return value, ok
+}
+
+// isPodOldEnough checks if the pod has been running for the duration in its min-age annotation.
+func (p *Predictor) isPodOldEnough(log logger.Logger, pod *api_v1.Pod) bool {
+	value, ok := p.getPodAnnotation(pod, MinAgeAnnotationKey)
+	if !ok {
+		return true
+	}
+	minAge, err := time.ParseDuration(value)
+	if err != nil {
+		// A pod asking for protection is protected, even if it asks in a wrong way.
+		log.Error(err, "Can't parse the min-age annotation as a duration, skipping this pod", "pod", pod.Name, "value", value)
+		return false
+	}
+	if time.Since(podStartTime(pod)) < minAge {
+		log.V(4).Info("Pod is younger than its min-age annotation", "pod", pod.Name, "minAge", value)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package predictor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reasons a pod marked as evicted is dropped, also the values of the reason label of metrics.DroppedPods.
+const (
+	droppedNoTarget        = "no_target"
+	droppedPDB             = "pdb"
+	droppedNodeBudget      = "node_budget"
+	droppedNamespaceBudget = "namespace_budget"
+	droppedWorkloadBudget  = "workload_budget"
+)
+
+// evictBudget counts the pods picked in the current deschedule term by node,
+// namespace and workload, against the limits of spec.rules.evictBudget.
+type evictBudget struct {
+	conf       config.ConfigEvictBudget
+	nodes      map[string]int
+	namespaces map[string]int
+	workloads  map[string]int
+}
+
+func newEvictBudget(conf config.ConfigEvictBudget) *evictBudget {
+	return &evictBudget{
+		conf:       conf,
+		nodes:      make(map[string]int),
+		namespaces: make(map[string]int),
+		workloads:  make(map[string]int),
+	}
+}
+
+// exceeded returns the reason if picking the pod exceeds any of the limits, or an empty string.
+func (b *evictBudget) exceeded(pod *api_v1.Pod) string {
+	if b.conf.PerNode > 0 && b.nodes[pod.Spec.NodeName] >= b.conf.PerNode {
+		return droppedNodeBudget
+	}
+	if b.conf.PerNamespace > 0 && b.namespaces[pod.Namespace] >= b.conf.PerNamespace {
+		return droppedNamespaceBudget
+	}
+	if b.conf.PerWorkload > 0 && b.workloads[workloadKey(pod)] >= b.conf.PerWorkload {
+		return droppedWorkloadBudget
+	}
+	return ""
+}
+
+func (b *evictBudget) take(pod *api_v1.Pod) {
+	b.nodes[pod.Spec.NodeName]++
+	b.namespaces[pod.Namespace]++
+	b.workloads[workloadKey(pod)]++
+}
+
+// workloadKey returns kind/namespace/name of the controller of the pod.
+func workloadKey(pod *api_v1.Pod) string {
+	controllerRef := v1.GetControllerOf(pod)
+	if controllerRef == nil {
+		return ""
+	}
+	return controllerRef.Kind + "/" + pod.Namespace + "/" + controllerRef.Name
+}
+
+// EvictionHistory is the time of the evictions in the past hour. The predictors
+// of every policy share one, so that spec.rules.evictBudget.perHour limits the
+// evictions in the cluster, and it outlives the predictors replaced on reload.
+// It is kept in memory only, a restarted descheduler or a new leader starts
+// with an empty history.
+type EvictionHistory struct {
+	mutex sync.Mutex
+	times []time.Time
+}
+
+// NewEvictionHistory creates an empty EvictionHistory.
+func NewEvictionHistory() *EvictionHistory {
+	return &EvictionHistory{}
+}
+
+// SetEvictionHistory replaces the history of evictions the predictor counts and records to.
+func (p *Predictor) SetEvictionHistory(history *EvictionHistory) {
+	p.evictionHistory = history
+}
+
+func (h *EvictionHistory) add(evictedAt time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.times = append(h.times, evictedAt)
+}
+
+// countSince drops the evictions before since and returns how many are left.
+func (h *EvictionHistory) countSince(since time.Time) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	times := h.times[:0]
+	for _, evictedAt := range h.times {
+		if evictedAt.After(since) {
+			times = append(times, evictedAt)
+		}
+	}
+	h.times = times
+	return len(times)
+}
+
+// hourlyRemaining returns how many more pods spec.rules.evictBudget.perHour allows
+// to evict in the past hour, -1 if it's unlimited.
+func (p *Predictor) hourlyRemaining() int {
+	evicted := p.evictionHistory.countSince(time.Now().Add(-time.Hour))
+	if p.conf.Rules.EvictBudget.PerHour <= 0 {
+		return -1
+	}
+	if remaining := p.conf.Rules.EvictBudget.PerHour - evicted; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// dropPod records the reason a pod marked as evicted is not picked.
+func dropPod(log logger.Logger, pod *api_v1.Pod, reason string) {
+	log.V(2).Info("Pod dropped from eviction", "pod", pod.Namespace+"/"+pod.Name, "reason", reason)
+	metrics.DroppedPods.Inc(reason)
+}
@@ -0,0 +1,89 @@
+package predictor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGetEvictPodsEvictBudget(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		budget config.ConfigEvictBudget
+		size   int
+	}{
+		{"no limit", config.ConfigEvictBudget{}, 2},
+		{"per node", config.ConfigEvictBudget{PerNode: 1}, 1},
+		{"per namespace", config.ConfigEvictBudget{PerNamespace: 1}, 1},
+		{"per workload", config.ConfigEvictBudget{PerWorkload: 1}, 2},
+	}
+	for _, test := range tests {
+		conf := config.DefaultConfig()
+		conf.Rules.EvictBudget = test.budget
+		p, _ := newTestPredictor(conf, clusterFixture()...)
+		assert.Len(t, getEvictPods(t, p), test.size, test.name)
+	}
+}
+
+func TestGetEvictPodsPerHour(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.EvictBudget.PerHour = 3
+	p, _ := newTestPredictor(conf, clusterFixture()...)
+	now := time.Now()
+	history := NewEvictionHistory()
+	history.times = []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute), now.Add(-time.Minute)}
+	p.SetEvictionHistory(history)
+	log := logger.WithValues("test", t.Name())
+	nodes, _ := p.GetBusyNodes(log)
+	// One of the three evictions is out of the past hour.
+	pods, err := p.GetEvictPods(log, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, pods, 1)
+	assert.Len(t, history.times, 2)
+
+	// Dry runs are not counted.
+	dryRun := conf
+	dryRun.DryRun = true
+	p.SetConfig(dryRun)
+	assert.Len(t, p.Evict(log, pods), 1)
+	assert.Len(t, history.times, 2)
+
+	p.SetConfig(conf)
+	p.Evict(log, pods)
+	assert.Len(t, history.times, 3)
+	pods, err = p.GetEvictPods(log, nodes)
+	assert.NoError(t, err)
+	assert.Empty(t, pods)
+
+	// The evictions of a predictor count for the others sharing the history,
+	// like the predictors of other policies.
+	other, _ := newTestPredictor(conf, clusterFixture()...)
+	other.SetEvictionHistory(history)
+	assert.Empty(t, getEvictPods(t, other))
+}
+
+// Pods found gone on eviction are reported evicted, but don't take the hourly budget.
+func TestEvictNotFoundNotCounted(t *testing.T) {
+	t.Parallel()
+	p, clientset := newTestPredictor(config.DefaultConfig(), clusterFixture()...)
+	history := NewEvictionHistory()
+	p.SetEvictionHistory(history)
+	clientset.EvictError = func(eviction *policy.Eviction) error {
+		if eviction.Name == "api-1" {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, eviction.Name)
+		}
+		return nil
+	}
+	pods := getEvictPods(t, p)
+	evicted := p.Evict(logger.WithValues("test", t.Name()), pods)
+	assert.Len(t, evicted, len(pods))
+	assert.Len(t, history.times, len(pods)-1)
+}
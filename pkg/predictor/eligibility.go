@@ -0,0 +1,102 @@
+package predictor
+
+import (
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ExcludeAnnotationKey excludes the node from descheduling when it's set to "true".
+const ExcludeAnnotationKey = "descheduler.lentil1016.cn/exclude"
+
+// Reasons a node is excluded from descheduling, also the values of the reason label of metrics.ExcludedNodes.
+const (
+	excludedNotReady   = "not_ready"
+	excludedAnnotation = "annotation"
+	excludedLabel      = "label"
+	excludedTaint      = "taint"
+	excludedAge        = "age"
+	excludedCordoned   = "cordoned"
+)
+
+var exclusionReasons = []string{excludedNotReady, excludedAnnotation, excludedLabel, excludedTaint, excludedAge, excludedCordoned}
+
+// nodeExclusions tells whether a node is eligible for descheduling, both as
+// the source and as the target of the evicted pods.
+type nodeExclusions struct {
+	selector  labels.Selector
+	taintKeys []string
+	minAge    time.Duration
+	cordoned  bool
+	now       time.Time
+}
+
+func (p *Predictor) newNodeExclusions() (*nodeExclusions, error) {
+	rules := p.conf.Rules.ExcludeNodes
+	e := &nodeExclusions{
+		selector:  labels.Nothing(),
+		taintKeys: rules.TaintKeys,
+		cordoned:  rules.Cordoned,
+		now:       time.Now(),
+	}
+	if rules.LabelSelector != "" {
+		selector, err := labels.Parse(rules.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		e.selector = selector
+	}
+	if rules.MinAge != "" {
+		minAge, err := time.ParseDuration(rules.MinAge)
+		if err != nil {
+			return nil, err
+		}
+		e.minAge = minAge
+	}
+	return e, nil
+}
+
+// reason returns why the node is excluded, or an empty string if the node is eligible.
+func (e *nodeExclusions) reason(node *api_v1.Node) string {
+	if !isNodeOperatable(node) {
+		return excludedNotReady
+	}
+	if node.Annotations[ExcludeAnnotationKey] == "true" {
+		return excludedAnnotation
+	}
+	if e.selector.Matches(labels.Set(node.Labels)) {
+		return excludedLabel
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, key := range e.taintKeys {
+			if key == taint.Key {
+				return excludedTaint
+			}
+		}
+	}
+	if e.minAge > 0 && e.now.Sub(node.CreationTimestamp.Time) < e.minAge {
+		return excludedAge
+	}
+	if e.cordoned && !isNodeSchedulable(node) {
+		return excludedCordoned
+	}
+	return ""
+}
+
+// reportExcludedNodes logs the reason of every excluded node, and exports the number of them by reasons.
+func reportExcludedNodes(log logger.Logger, excluded map[string]string) {
+	counts := make(map[string]int)
+	for name, reason := range excluded {
+		log.V(2).Info("Node is excluded from descheduling", "node", name, "reason", reason)
+		counts[reason]++
+	}
+	for _, reason := range exclusionReasons {
+		metrics.ExcludedNodes.Set(float64(counts[reason]), reason)
+	}
+	if len(excluded) > 0 {
+		log.Info("Nodes excluded", "excluded", len(excluded))
+	}
+}
@@ -0,0 +1,138 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EvictedAnnotationKey is the annotation stamped on pods right before they are evicted,
+// when spec.rules.annotateEvictedPods is enabled.
+const EvictedAnnotationKey = "descheduler.lentil1016.cn/evicted"
+
+const eventSourceComponent = "descheduler"
+
+// EventRecorder records Kubernetes Events about eviction decisions,
+// the default one creates the Events through the API server.
+type EventRecorder interface {
+	Event(object *api_v1.ObjectReference, eventType, reason, message string)
+}
+
+// SetEventRecorder replaces the recorder of the eviction Events.
+func (p *Predictor) SetEventRecorder(recorder EventRecorder) {
+	p.eventRecorder = recorder
+}
+
+type apiEventRecorder struct {
+	client kubernetes.Interface
+}
+
+// NewEventRecorder creates an EventRecorder creating Events through the API server.
+// client-go/tools/record is not vendored, Events are not aggregated as it does.
+func NewEventRecorder(clientset kubernetes.Interface) EventRecorder {
+	return &apiEventRecorder{client: clientset}
+}
+
+func (er *apiEventRecorder) Event(object *api_v1.ObjectReference, eventType, reason, message string) {
+	namespace := object.Namespace
+	if namespace == "" {
+		// Events of cluster scoped objects like nodes go to the default namespace.
+		namespace = v1.NamespaceDefault
+	}
+	now := v1.Now()
+	event := &api_v1.Event{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", object.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *object,
+		Reason:         reason,
+		Message:        message,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+		Source:         api_v1.EventSource{Component: eventSourceComponent},
+	}
+	if _, err := er.client.CoreV1().Events(namespace).Create(event); err != nil {
+		logger.Error(err, "Failed to record event", "reason", reason, "kind", object.Kind, "name", object.Name)
+	}
+}
+
+// evictDecision tells why a pod is picked to be evicted in the current deschedule term.
+type evictDecision struct {
+	strategy  evictStrategy
+	nodeName  string
+	nodeClass string
+	// targetNode is the node the pod is expected to be scheduled on
+	targetNode string
+}
+
+func (d evictDecision) message() string {
+	message := fmt.Sprintf("Evicted by descheduler from %v node %v, %v", d.nodeClass, d.nodeName, d.strategy.description)
+	if d.targetNode != "" {
+		message += fmt.Sprintf(", expected to be scheduled on node %v", d.targetNode)
+	}
+	return message
+}
+
+// recordEviction records the eviction on the pod, the workload owning it and the node it ran on.
+func (p *Predictor) recordEviction(pod *api_v1.Pod, d evictDecision) {
+	message := fmt.Sprintf("%v: %v/%v", d.message(), pod.Namespace, pod.Name)
+	p.eventRecorder.Event(podReference(pod), api_v1.EventTypeNormal, d.strategy.reason, d.message())
+	if controllerRef := v1.GetControllerOf(pod); controllerRef != nil {
+		p.eventRecorder.Event(&api_v1.ObjectReference{
+			APIVersion: controllerRef.APIVersion,
+			Kind:       controllerRef.Kind,
+			Namespace:  pod.Namespace,
+			Name:       controllerRef.Name,
+			UID:        controllerRef.UID,
+		}, api_v1.EventTypeNormal, d.strategy.reason, message)
+	}
+	if node, err := p.nodeLister.Get(d.nodeName); err == nil {
+		p.eventRecorder.Event(&api_v1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       node.Name,
+			UID:        node.UID,
+		}, api_v1.EventTypeNormal, d.strategy.reason, message)
+	}
+}
+
+// recordEvictionFailure records the failed eviction on the pod.
+func (p *Predictor) recordEvictionFailure(pod *api_v1.Pod, err error) {
+	p.eventRecorder.Event(podReference(pod), api_v1.EventTypeWarning, "DeschedulerEvictionFailed", err.Error())
+}
+
+func podReference(pod *api_v1.Pod) *api_v1.ObjectReference {
+	return &api_v1.ObjectReference{
+		APIVersion:      "v1",
+		Kind:            "Pod",
+		Namespace:       pod.Namespace,
+		Name:            pod.Name,
+		UID:             pod.UID,
+		ResourceVersion: pod.ResourceVersion,
+	}
+}
+
+// annotateEvictedPod stamps the eviction decision on the pod, so that tools watching
+// the pod deletion can tell why it has been evicted.
+func (p *Predictor) annotateEvictedPod(pod *api_v1.Pod, d evictDecision) error {
+	value := fmt.Sprintf("%v %v", time.Now().Format(time.RFC3339), d.message())
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{EvictedAnnotationKey: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = p.client.CoreV1().Pods(pod.Namespace).Patch(pod.Name, k8stypes.MergePatchType, patch)
+	return err
+}
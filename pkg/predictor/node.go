@@ -1,14 +1,13 @@
 package predictor
 
 import (
-	"fmt"
 	"sort"
 
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
 	api_v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
-	v1_resource "k8s.io/kubernetes/pkg/api/v1/resource"
 )
 
 type nodeScore struct {
@@ -17,38 +16,59 @@ type nodeScore struct {
 }
 
 // Splite node into high spared nodes list and low spared state nodes list
-func GetBusyNodes() ([]*api_v1.Node, bool) {
-	operatableNodes, _ := getOperatableNodes()
+func (p *Predictor) GetBusyNodes(log logger.Logger) ([]*api_v1.Node, bool) {
+	p.nodeClasses = make(map[string]string)
+	p.nodeUsages = make(map[string]nodeUsage)
+	operatableNodes, excluded, err := p.selectNodes()
+	if err != nil {
+		log.Error(err, "Deschedule event aborted, failed to list nodes")
+		return []*api_v1.Node{}, false
+	}
+	reportExcludedNodes(log, excluded)
+	scorer, err := newNodeScorer(p.conf.Triggers.Scorer)
+	if err != nil {
+		log.Error(err, "Deschedule event aborted, failed to create node scorer")
+		return []*api_v1.Node{}, false
+	}
 	if len(operatableNodes) < 2 {
-		fmt.Println("Deschedule event droped because Operatable node is less than 2")
+		log.Info("Deschedule event dropped because operatable node is less than 2", "operatableNodes", len(operatableNodes))
 		return []*api_v1.Node{}, false
 	}
 	// ranking nodes by most spared and most usage
 	var sparedRank, usageRank, normalRank []nodeScore
 	for _, node := range operatableNodes {
 		nodeName := node.ObjectMeta.Name
-		cpuUsage, memUsage, podUsage, err := getNodeUsage(node)
+		usage, err := p.getNodeUsage(log, node)
 		if err != nil {
-			fmt.Println("Deschedule event aborted, failed to get node usage, ", err)
+			log.Error(err, "Deschedule event aborted, failed to get node usage", "node", nodeName)
 			return []*api_v1.Node{}, false
 		}
-		usageScore, sparedScore, normalScore := scoreNode(cpuUsage, memUsage, podUsage)
+		usageScore, sparedScore, normalScore := p.scoreNode(scorer, usage)
+		p.nodeUsages[nodeName] = usage
 
 		if usageScore != 0 {
 			// High Usage node, marked if any resource is running low.
-			fmt.Printf("Node %v is marked as a high usage node\n", nodeName)
-			usageRank = append(usageRank, nodeScore{node, sparedScore})
+			log.V(2).Info("Node is marked as a high usage node", "node", nodeName, "usage", usage)
+			p.nodeClasses[nodeName] = "usage"
+			usageRank = append(usageRank, nodeScore{node, usageScore})
 		} else if sparedScore != 0 && isNodeSchedulable(node) {
 			// High spared node, marked if some resource is highly spared
 			// and node is schedulable, and no resource is running low.
-			fmt.Printf("Node %v is marked as a high spared node\n", nodeName)
+			log.V(2).Info("Node is marked as a high spared node", "node", nodeName, "usage", usage)
+			p.nodeClasses[nodeName] = "spared"
 			sparedRank = append(sparedRank, nodeScore{node, sparedScore})
 		} else {
 			// Normal node, returned as usage node when there is no usage node.
-			fmt.Printf("Node %v is marked as a normal node\n", nodeName)
+			log.V(2).Info("Node is marked as a normal node", "node", nodeName, "usage", usage)
+			p.nodeClasses[nodeName] = "normal"
 			normalRank = append(normalRank, nodeScore{node, normalScore})
 		}
 	}
+	metrics.Nodes.Set(float64(len(usageRank)), "usage")
+	metrics.Nodes.Set(float64(len(sparedRank)), "spared")
+	metrics.Nodes.Set(float64(len(normalRank)), "normal")
+	log.Info("Nodes classified", "usage", len(usageRank), "spared", len(sparedRank), "normal", len(normalRank))
+
 	// Do ranking
 	sort.Slice(sparedRank, func(i, j int) bool { return sparedRank[i].score > sparedRank[j].score })
 	sort.Slice(usageRank, func(i, j int) bool { return usageRank[i].score > usageRank[j].score })
@@ -68,18 +88,18 @@ func GetBusyNodes() ([]*api_v1.Node, bool) {
 
 	if len(normalNodes) == 0 {
 		if len(usageNodes) == 0 {
-			fmt.Println("Deschedule event aborted, all nodes are spared, nothing to deschedule")
+			log.Info("Deschedule event aborted, all nodes are spared, nothing to deschedule")
 			return []*api_v1.Node{}, false
 		} else if len(sparedNodes) == 0 {
-			fmt.Println("Deschedule event aborted, all nodes are busy, can't deschedule")
+			log.Info("Deschedule event aborted, all nodes are busy, can't deschedule")
 			return []*api_v1.Node{}, false
 		}
 	} else if len(usageNodes) == 0 {
 		if len(sparedNodes) == 0 {
-			fmt.Println("Deschedule event aborted, all nodes are normal, nothing to deschedule")
+			log.Info("Deschedule event aborted, all nodes are normal, nothing to deschedule")
 			return []*api_v1.Node{}, false
 		} else {
-			fmt.Println("All nodes reserved sufficient resource. Try deschedule anyway.")
+			log.Info("All nodes reserved sufficient resource, try deschedule anyway")
 			return normalNodes, true
 		}
 	}
@@ -90,61 +110,89 @@ func IsNodeReady(node *api_v1.Node) bool {
 	return isNodeOperatable(node) && isNodeSchedulable(node)
 }
 
-func getNodeUsage(node *api_v1.Node) (float64, float64, float64, error) {
-	pods, err := getPodsOnNode(node)
+func (p *Predictor) getNodeUsage(log logger.Logger, node *api_v1.Node) (nodeUsage, error) {
+	pods, err := p.getPodsOnNode(node)
 	if err != nil {
-		return 0, 0, 0, err
+		return nil, err
 	}
-	totalReqs := map[api_v1.ResourceName]resource.Quantity{}
+	usage := p.getEmptyUsage(node)
 	for _, pod := range pods {
-		requests, _ := v1_resource.PodRequestsAndLimits(pod)
-		for res, req := range requests {
-			if res == api_v1.ResourceCPU || res == api_v1.ResourceMemory {
-				if oldReq, ok := totalReqs[res]; !ok {
-					totalReqs[res] = *req.Copy()
-				} else {
-					oldReq.Add(req)
-					totalReqs[res] = oldReq
-				}
+		usage = addUsage(usage, p.getPodUsage(node, pod))
+	}
+
+	weights := p.conf.Triggers.MetricsWeight
+	if weights.CPU > 0 || weights.Memory > 0 {
+		metricsUsage, err := p.metricsClient.GetNodeMetrics(node.ObjectMeta.Name)
+		if err != nil {
+			// Requests still tell the scheduler's view of the node.
+			log.Error(err, "Using the usage calculated from requests", "node", node.ObjectMeta.Name)
+			return usage, nil
+		}
+		nodeCapacity := getNodeCapacity(node)
+		for name, weight := range map[api_v1.ResourceName]float64{api_v1.ResourceCPU: weights.CPU, api_v1.ResourceMemory: weights.Memory} {
+			requestUsage, ok := usage[string(name)]
+			used, reported := metricsUsage[name]
+			if !ok || !reported {
+				continue
 			}
+			usage[string(name)] = blendUsage(requestUsage, percentageOf(used, nodeCapacity[name]), weight)
 		}
 	}
-	nodeCapacity := node.Status.Capacity
+	return usage, nil
+}
+
+// getNodeCapacity returns the allocatable resources of the node, or the capacity if it's not reported.
+func getNodeCapacity(node *api_v1.Node) api_v1.ResourceList {
 	if len(node.Status.Allocatable) > 0 {
-		nodeCapacity = node.Status.Allocatable
-	}
-	totalCPUReq := totalReqs[api_v1.ResourceCPU]
-	totalMemReq := totalReqs[api_v1.ResourceMemory]
-	totalPods := len(pods)
-	cpuUsage := float64((float64(totalCPUReq.MilliValue()) * 100) / float64(nodeCapacity.Cpu().MilliValue()))
-	memUsage := float64(float64(totalMemReq.Value()) / float64(nodeCapacity.Memory().Value()) * 100)
-	podUsage := float64((float64(totalPods) * 100) / float64(nodeCapacity.Pods().Value()))
-	return cpuUsage, memUsage, podUsage, nil
+		return node.Status.Allocatable
+	}
+	return node.Status.Capacity
 }
 
-func getPodNode(pod *api_v1.Pod) (*api_v1.Node, error) {
-	node, err := nodeLister.Get(pod.Spec.NodeName)
+func (p *Predictor) getPodNode(pod *api_v1.Pod) (*api_v1.Node, error) {
+	node, err := p.nodeLister.Get(pod.Spec.NodeName)
 	return node, err
 }
 
-func getOperatableNodes() ([]*api_v1.Node, error) {
+// getOperatableNodes returns the selected nodes that are not excluded from descheduling.
+func (p *Predictor) getOperatableNodes() ([]*api_v1.Node, error) {
+	nodes, _, err := p.selectNodes()
+	return nodes, err
+}
+
+// selectNodes returns the selected nodes that are not excluded from descheduling,
+// and the reasons of the excluded ones by node names.
+func (p *Predictor) selectNodes() ([]*api_v1.Node, map[string]string, error) {
 	// Get all nodes
+	// Nodes are selected here instead of in the informer, so that the
+	// node selector can be changed by reloading the config.
+	selector, err := labels.Parse(p.conf.Rules.NodeSelector)
+	if err != nil {
+		return []*api_v1.Node{}, nil, err
+	}
+	exclusions, err := p.newNodeExclusions()
+	if err != nil {
+		return []*api_v1.Node{}, nil, err
+	}
 	var nodes []*api_v1.Node
-	err := cache.ListAll(indexers.nodeIndexer, labels.Everything(), func(m interface{}) {
+	err = cache.ListAll(p.indexers.NodeIndexer, selector, func(m interface{}) {
 		nodes = append(nodes, m.(*api_v1.Node))
 	})
 	if err != nil {
-		return []*api_v1.Node{}, err
+		return []*api_v1.Node{}, nil, err
 	}
 
-	// Select the nodes that is ready
-	readyNodes := make([]*api_v1.Node, 0, len(nodes))
+	// Select the nodes that is ready and not excluded
+	operatableNodes := make([]*api_v1.Node, 0, len(nodes))
+	excluded := make(map[string]string)
 	for _, node := range nodes {
-		if isNodeOperatable(node) {
-			readyNodes = append(readyNodes, node)
+		if reason := exclusions.reason(node); reason != "" {
+			excluded[node.Name] = reason
+			continue
 		}
+		operatableNodes = append(operatableNodes, node)
 	}
-	return nodes, nil
+	return operatableNodes, excluded, nil
 }
 
 func isNodeOperatable(node *api_v1.Node) bool {
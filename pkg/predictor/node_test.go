@@ -0,0 +1,202 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The normal node allows only 2 pods, otherwise it is highly spared on pods.
+func TestGetBusyNodes(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestPredictor(config.DefaultConfig(),
+		fake.NewNode("busy", "4", "8Gi", 110),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		fake.NewNode("normal", "4", "8Gi", 2),
+		fake.NewPod("default", "a", "busy", "3", "1Gi"),
+		fake.NewPod("default", "b", "normal", "2", "4Gi"),
+	)
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "busy", nodes[0].Name)
+	}
+	assert.Equal(t, map[string]string{"busy": "usage", "spared": "spared", "normal": "normal"}, p.nodeClasses)
+}
+
+func TestGetBusyNodesWithoutUsageNode(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestPredictor(config.DefaultConfig(),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		fake.NewNode("normal", "4", "8Gi", 2),
+		fake.NewPod("default", "b", "normal", "2", "4Gi"),
+	)
+	// Normal nodes are descheduled when spared nodes can take their pods.
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "normal", nodes[0].Name)
+	}
+}
+
+func TestGetBusyNodesNothingToDeschedule(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		objs []interface{}
+	}{
+		{"single node", []interface{}{
+			fake.NewNode("busy", "4", "8Gi", 110),
+			fake.NewPod("default", "a", "busy", "3", "1Gi"),
+		}},
+		{"all nodes spared", []interface{}{
+			fake.NewNode("spared-1", "4", "8Gi", 110),
+			fake.NewNode("spared-2", "4", "8Gi", 110),
+		}},
+		{"all nodes busy", []interface{}{
+			fake.NewNode("busy-1", "4", "8Gi", 110),
+			fake.NewNode("busy-2", "4", "8Gi", 110),
+			fake.NewPod("default", "a", "busy-1", "3", "1Gi"),
+			fake.NewPod("default", "b", "busy-2", "3", "1Gi"),
+		}},
+	}
+	for _, test := range tests {
+		p, _ := newTestPredictor(config.DefaultConfig(), test.objs...)
+		nodes, ok := p.GetBusyNodes(logger.WithValues())
+		assert.False(t, ok, test.name)
+		assert.Empty(t, nodes, test.name)
+	}
+}
+
+func TestGetBusyNodesNodeSelector(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.NodeSelector = "kubernetes.io/hostname!=spared"
+	p, _ := newTestPredictor(conf,
+		fake.NewNode("busy", "4", "8Gi", 110),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		fake.NewPod("default", "a", "busy", "3", "1Gi"),
+	)
+	// Only the busy node is selected, there is no other node to take its pods.
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.False(t, ok)
+	assert.Empty(t, nodes)
+
+	// The selector is swapped in by reloading the config.
+	conf.Rules.NodeSelector = ""
+	p.SetConfig(conf)
+	nodes, ok = p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	assert.Len(t, nodes, 1)
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	assert.NoError(t, ValidateConfig(conf))
+	conf.Rules.NodeSelector = "role in (a"
+	assert.Error(t, ValidateConfig(conf))
+}
+
+func TestGetBusyNodesExcludedNodes(t *testing.T) {
+	t.Parallel()
+	newSpared := func(name string) *api_v1.Node {
+		return fake.NewNode(name, "4", "8Gi", 110)
+	}
+	notReady := newSpared("not-ready")
+	notReady.Status.Conditions[0].Status = api_v1.ConditionFalse
+	annotated := newSpared("annotated")
+	annotated.Annotations = map[string]string{ExcludeAnnotationKey: "true"}
+	labelled := newSpared("labelled")
+	labelled.Labels["node-role.kubernetes.io/master"] = ""
+	tainted := fake.SetTaint(newSpared("tainted"), "dedicated", api_v1.TaintEffectPreferNoSchedule)
+	young := newSpared("young")
+	young.CreationTimestamp = v1.Now()
+	cordoned := newSpared("cordoned")
+	cordoned.Spec.Unschedulable = true
+
+	conf := config.DefaultConfig()
+	conf.Rules.ExcludeNodes = config.ConfigNodeExclusions{
+		LabelSelector: "node-role.kubernetes.io/master",
+		TaintKeys:     []string{"dedicated"},
+		MinAge:        "10m",
+		Cordoned:      true,
+	}
+	p, _ := newTestPredictor(conf,
+		fake.NewNode("busy", "4", "8Gi", 110),
+		notReady, annotated, labelled, tainted, young, cordoned,
+		fake.NewPod("default", "a", "busy", "3", "1Gi"),
+	)
+	// Only the busy node is eligible, there is no other node to take its pods.
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.False(t, ok)
+	assert.Empty(t, nodes)
+	_, excluded, err := p.selectNodes()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"not-ready": excludedNotReady,
+		"annotated": excludedAnnotation,
+		"labelled":  excludedLabel,
+		"tainted":   excludedTaint,
+		"young":     excludedAge,
+		"cordoned":  excludedCordoned,
+	}, excluded)
+
+	// Without the rules, only the not ready node is excluded.
+	p.SetConfig(config.DefaultConfig())
+	nodes, ok = p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	assert.Len(t, nodes, 1)
+	_, excluded, _ = p.selectNodes()
+	assert.Equal(t, map[string]string{"not-ready": excludedNotReady, "annotated": excludedAnnotation}, excluded)
+}
+
+// Nodes are classified by every resource in the thresholds they have, extended
+// resources included, and ignore the ones they lack.
+func TestGetBusyNodesExtendedResources(t *testing.T) {
+	t.Parallel()
+	gpu := fake.NewNode("gpu", "4", "8Gi", 110)
+	gpu.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("4")
+	pod := fake.NewPod("default", "a", "gpu", "100m", "1Gi")
+	pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("4")
+	objs := []interface{}{gpu, fake.NewNode("plain", "4", "8Gi", 110), pod}
+
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	_, ok := p.GetBusyNodes(logger.WithValues())
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{"gpu": "spared", "plain": "spared"}, p.nodeClasses)
+
+	conf := config.DefaultConfig()
+	conf.Triggers.MinSparedPercentage["nvidia.com/gpu"] = 10
+	p, _ = newTestPredictor(conf, objs...)
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "gpu", nodes[0].Name)
+	}
+	assert.Equal(t, map[string]string{"gpu": "usage", "plain": "spared"}, p.nodeClasses)
+	assert.Equal(t, 100.0, p.nodeUsages["gpu"]["nvidia.com/gpu"])
+	assert.NotContains(t, p.nodeUsages["plain"], "nvidia.com/gpu")
+}
+
+// Busy nodes are found by the gpu threshold alone once the defaults are turned off.
+func TestGetBusyNodesDefaultThresholdsOff(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Triggers.MinSparedPercentage = config.ConfigResourcePercentage{"cpu": 0, "memory": 0, config.ResourcePod: 0, "nvidia.com/gpu": 10}
+	p, _ := newTestPredictor(conf,
+		fake.NewNode("cpu", "4", "8Gi", 110),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		fake.NewPod("default", "a", "cpu", "3900m", "7900Mi"),
+	)
+	// The cpu node running low on cpu and memory is only highly spared on pods.
+	_, ok := p.GetBusyNodes(logger.WithValues())
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{"cpu": "spared", "spared": "spared"}, p.nodeClasses)
+}
@@ -0,0 +1,73 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// disruptionAllowance tracks how many more disruptions each PodDisruptionBudget
+// allows in the current deschedule term, keyed by namespace/name of the budget.
+type disruptionAllowance map[string]int32
+
+// takeDisruption reserves one disruption for the pod from every budget that covers it.
+// It returns false without reserving anything if any of those budgets has no
+// disruption left, because the eviction API would refuse the eviction.
+func (p *Predictor) takeDisruption(da disruptionAllowance, log logger.Logger, pod *api_v1.Pod) bool {
+	pdbs, err := p.getPodDisruptionBudgets(log, pod)
+	if err != nil {
+		log.Error(err, "Get PodDisruptionBudgets failed, skipping this pod", "pod", pod.Name)
+		return false
+	}
+	for _, pdb := range pdbs {
+		if da.remaining(pdb) <= 0 {
+			log.V(2).Info("PodDisruptionBudget allows no more disruption, skipping this pod", "pod", pod.Name, "pdb", pdb.Name)
+			return false
+		}
+	}
+	for _, pdb := range pdbs {
+		da[pdbKey(pdb)] = da.remaining(pdb) - 1
+	}
+	return true
+}
+
+func (da disruptionAllowance) remaining(pdb *policy.PodDisruptionBudget) int32 {
+	if allowed, ok := da[pdbKey(pdb)]; ok {
+		return allowed
+	}
+	// The disruption controller has not processed the latest spec yet,
+	// the eviction API refuses evictions covered by such a budget.
+	if pdb.Status.ObservedGeneration < pdb.Generation {
+		return 0
+	}
+	return pdb.Status.PodDisruptionsAllowed
+}
+
+func pdbKey(pdb *policy.PodDisruptionBudget) string {
+	return pdb.Namespace + "/" + pdb.Name
+}
+
+// getPodDisruptionBudgets returns the budgets in the pod's namespace that select the pod.
+func (p *Predictor) getPodDisruptionBudgets(log logger.Logger, pod *api_v1.Pod) ([]*policy.PodDisruptionBudget, error) {
+	objs, err := p.indexers.PDBIndexer.ByIndex("byNamespace", pod.Namespace)
+	if err != nil {
+		return []*policy.PodDisruptionBudget{}, err
+	}
+	var pdbs []*policy.PodDisruptionBudget
+	for _, obj := range objs {
+		pdb := obj.(*policy.PodDisruptionBudget)
+		selector, err := v1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			log.Error(err, "Invalid selector of PodDisruptionBudget, ignoring it", "pdb", pdb.Name)
+			continue
+		}
+		// An empty selector matches nothing, the same as the disruption controller does.
+		if selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		pdbs = append(pdbs, pdb)
+	}
+	return pdbs, nil
+}
@@ -0,0 +1,55 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// check if there is peer pods on same node, then mark as evicted
+func (p *Predictor) evictWithPeerOnOneNode(log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
+	wpm := make(map[string]*api_v1.Pod, 0)
+	workloadEvictedKeys := make(map[string]bool, 0)
+	var remains, evicts []*api_v1.Pod
+	for _, pod := range pods {
+		// if is a pod created by a workload
+		if workloadKey := p.GetPodWorkloadKey(pod); workloadKey != "" {
+			// if there is another pod's workload is the same with this one
+			if peerPod, ok := wpm[workloadKey]; ok {
+				log.V(2).Info("Find peer on current node, pod marked as evicted", "pod", pod.Name, "peer", peerPod.Name, "workload", workloadKey)
+				workloadEvictedKeys[workloadKey] = true
+				evicts = append(evicts, pod)
+			} else {
+				wpm[workloadKey] = pod
+			}
+		} else {
+			// not processed by this evict function
+			remains = append(remains, pod)
+		}
+	}
+	for workloadKey, pod := range wpm {
+		if _, ok := workloadEvictedKeys[workloadKey]; ok {
+			// this pod's peer on this node is marked as evicted,
+			// should remain this one here.
+			log.V(2).Info("Pin pod on current node for this schedule term", "pod", pod.Name, "workload", workloadKey)
+		} else {
+			remains = append(remains, pod)
+		}
+	}
+	return remains, evicts
+}
+
+// Check if there is peer pods in cluster, then mark as evicted
+func (p *Predictor) evictWithPeer(log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
+	var remains, evicts []*api_v1.Pod
+	for _, pod := range pods {
+		w := p.getPodWorkload(pod)
+		if w != nil && w.ReadyReplicas > 1 {
+			// pod have living peer on other nodes.
+			log.V(2).Info("Find living peers, pod marked as evicted", "pod", pod.Name, "workload", w.Key())
+			evicts = append(evicts, pod)
+		} else {
+			remains = append(remains, pod)
+		}
+	}
+	return remains, evicts
+}
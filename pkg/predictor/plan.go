@@ -0,0 +1,32 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// Move is a pod planned to be evicted from its node, and the node it's expected
+// to be scheduled on. The scheduler is free to schedule it anywhere else.
+type Move struct {
+	Pod  *api_v1.Pod
+	From string
+	To   string
+}
+
+// Plan returns the moves planned in the current deschedule term, in the order the pods are picked.
+func (p *Predictor) Plan() []Move {
+	return p.plan
+}
+
+// logPlan logs the usage of every busy node projected as the pods picked from it are gone.
+func (p *Predictor) logPlan(log logger.Logger, candidates []*nodeCandidates) {
+	for _, c := range candidates {
+		evictions := 0
+		for _, move := range p.plan {
+			if move.From == c.node.ObjectMeta.Name {
+				evictions++
+			}
+		}
+		c.log.Info("Node planned", "evictions", evictions, "usage", c.usage, "underUsageLine", p.overUsageLine(c.usage) <= 0)
+	}
+}
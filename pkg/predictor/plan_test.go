@@ -0,0 +1,45 @@
+package predictor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEvictPodsEvictOnlyNeeded(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.EvictOnlyNeeded = true
+	p, _ := newTestPredictor(conf, twoBusyNodesFixture()...)
+	// Each busy node gives only the pod bringing it back under its usage line.
+	pods := getEvictPods(t, p)
+	assert.Len(t, pods, 2)
+	assert.Equal(t, map[string]int{"busy-1": 1, "busy-2": 1}, countByNode(p))
+
+	plan := p.Plan()
+	if assert.Len(t, plan, 2) {
+		for i, move := range plan {
+			assert.Equal(t, pods[i], move.Pod)
+			assert.Equal(t, pods[i].Spec.NodeName, move.From)
+			assert.True(t, strings.HasPrefix(move.To, "spared-"), move.To)
+			assert.Contains(t, p.decisions[move.Pod.UID].message(), "expected to be scheduled on node "+move.To)
+		}
+	}
+}
+
+func TestGetEvictPodsTargetUsageLine(t *testing.T) {
+	t.Parallel()
+	objs := twoBusyNodesFixture()
+	// Only spared-1 is left, running 7Gi of memory. It takes 4 of the 1Gi pods
+	// before going over its 70% memory line, though it has room for more.
+	objs = append(objs[:3], objs[4:]...)
+	conf := config.DefaultConfig()
+	conf.Rules.MaxEvictSize = 10
+	p, _ := newTestPredictor(conf, objs...)
+	assert.Len(t, getEvictPods(t, p), 4)
+	for _, move := range p.Plan() {
+		assert.Equal(t, "spared-1", move.To)
+	}
+}
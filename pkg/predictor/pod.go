@@ -2,57 +2,135 @@ package predictor
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/metrics"
 	"github.com/lentil1016/descheduler/pkg/predicates"
 	api_v1 "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 )
 
+type evictStrategy struct {
+	name  string
+	evict func(p *Predictor, log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod)
+	// reason and description of the Events recorded on the evicted pods
+	reason      string
+	description string
+}
+
+// evictStrategies mark the pods to evict in this order. The pods marked are
+// then ranked by priority, QoS class and age, see rankedPods.Less, the order of
+// the strategies only breaks the ties.
+var evictStrategies = []evictStrategy{
+	{"evictUnfitPods", (*Predictor).evictUnfitPods, "DeschedulerEvictUnfitPod",
+		"the pod doesn't match the node affinity of its node and a prefered node is found"},
+	{"evictUntoleratedPods", (*Predictor).evictUntoleratedPods, "DeschedulerEvictUntoleratedTaint",
+		"the pod doesn't tolerate the NoSchedule taints of its node and a node it tolerates is found"},
+	{"evictAntiAffinityViolations", (*Predictor).evictAntiAffinityViolations, "DeschedulerEvictAntiAffinity",
+		"the pod violates the required pod anti-affinity on its node and a node without violation is found"},
+	{"evictWithPeerOnOneNode", (*Predictor).evictWithPeerOnOneNode, "DeschedulerEvictPeerOnOneNode",
+		"another pod of the same workload runs on the node"},
+	{"evictWithPeer", (*Predictor).evictWithPeer, "DeschedulerEvictPeerInCluster",
+		"the workload has other ready pods in the cluster"},
+}
+
 // get evictable pods and rank them, then get the dedired number of pods to evict
-func GetEvictPods(nodes []*api_v1.Node) ([]*api_v1.Pod, error) {
-	evictSize := conf.Rules.MaxEvictSize
-	var evictPods []*api_v1.Pod
+func (p *Predictor) GetEvictPods(log logger.Logger, nodes []*api_v1.Node) ([]*api_v1.Pod, error) {
+	evictSize, limit := p.conf.Rules.MaxEvictSize, "maxEvictSize"
+	if remaining := p.hourlyRemaining(); remaining >= 0 && remaining < evictSize {
+		if remaining == 0 {
+			log.Info("Deschedule event aborted, evictBudget.perHour is used up", "perHour", p.conf.Rules.EvictBudget.PerHour)
+			return []*api_v1.Pod{}, nil
+		}
+		evictSize, limit = remaining, "evictBudget.perHour"
+	}
+	budget := newEvictBudget(p.conf.Rules.EvictBudget)
+	allowance := disruptionAllowance{}
+	p.decisions = make(map[k8stypes.UID]evictDecision)
+	p.plan = []Move{}
+	simulator := p.newPlacementSimulator(log)
+	candidates := make([]*nodeCandidates, 0, len(nodes))
 	for _, node := range nodes {
-		pods, err := getEvictablePods(node)
-		if err != nil {
-			fmt.Printf("Get evictable pods on %v failed, skipping this node. %v\n", node.ObjectMeta.Name, err)
+		candidates = append(candidates, p.newNodeCandidates(log, node))
+	}
+	var evictPods []*api_v1.Pod
+	for c := p.nextCandidates(candidates); c != nil; c = p.nextCandidates(candidates) {
+		pod, strategy := c.pods[c.next], c.strategies[c.next]
+		c.next++
+		// A pod that exceeds the evict budget, has nowhere else to go, or would
+		// violate a PodDisruptionBudget is dropped, the next ranked pod takes its place.
+		if reason := budget.exceeded(pod); reason != "" {
+			dropPod(c.log, pod, reason)
+			continue
+		}
+		target := p.findNode(c.log, simulator, pod)
+		if target == nil {
+			dropPod(c.log, pod, droppedNoTarget)
+			continue
+		}
+		if !p.takeDisruption(allowance, c.log, pod) {
+			dropPod(c.log, pod, droppedPDB)
+			continue
+		}
+		p.reserve(simulator, pod, target)
+		budget.take(pod)
+		p.take(c, pod)
+		p.decisions[pod.UID] = evictDecision{
+			strategy:   strategy,
+			nodeName:   c.node.ObjectMeta.Name,
+			nodeClass:  p.nodeClasses[c.node.ObjectMeta.Name],
+			targetNode: target.Node.Name,
 		}
-		rankedPods := rankEvictablePods(pods)
-		evictPods = append(evictPods, rankedPods...)
+		p.plan = append(p.plan, Move{Pod: pod, From: c.node.ObjectMeta.Name, To: target.Node.Name})
+		evictPods = append(evictPods, pod)
+		c.log.Info("Pod picked to be evicted", "pod", pod.Namespace+"/"+pod.Name, "strategy", strategy.name, "target", target.Node.Name)
 		if len(evictPods) >= evictSize {
-			fmt.Printf("maxEvictSize decide only top %v pods that marked as evict will be evicted.\n", evictSize)
-			evictPods = evictPods[:evictSize]
+			log.Info(limit+" reached, only the top ranked pods will be evicted", limit, evictSize)
 			break
 		}
+		if p.settled(c) {
+			c.log.V(2).Info("Node is projected to be back under its usage line")
+		}
 	}
+	p.logPlan(log, candidates)
 	return evictPods, nil
 }
 
-func rankEvictablePods(pods []*api_v1.Pod) []*api_v1.Pod {
+// rankEvictablePods returns the pods marked as evicted and the strategies marked them,
+// in the order they are evicted.
+func (p *Predictor) rankEvictablePods(log logger.Logger, pods []*api_v1.Pod) ([]*api_v1.Pod, []evictStrategy) {
 	evicts := []*api_v1.Pod{}
-	remains, newEvicts := evictUnfitPods(pods)
-	evicts = append(evicts, newEvicts...)
-	remains, newEvicts = evictWithPeerOnOneNode(remains)
-	evicts = append(evicts, newEvicts...)
-	remains, newEvicts = evictWithPeer(remains)
-	evicts = append(evicts, newEvicts...)
-	return evicts
+	strategies := []evictStrategy{}
+	remains := pods
+	for _, strategy := range evictStrategies {
+		var newEvicts []*api_v1.Pod
+		remains, newEvicts = strategy.evict(p, log, remains)
+		metrics.SelectedPods.Add(float64(len(newEvicts)), strategy.name)
+		evicts = append(evicts, newEvicts...)
+		for range newEvicts {
+			strategies = append(strategies, strategy)
+		}
+	}
+	sortRankedPods(evicts, strategies)
+	return evicts, strategies
 }
 
 // check if there is pod unfit its node, then mark as evicted
-func evictUnfitPods(pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
+func (p *Predictor) evictUnfitPods(log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
 	var remains, evicts []*api_v1.Pod
 	for _, pod := range pods {
 		if pod.Spec.Affinity != nil &&
 			pod.Spec.Affinity.NodeAffinity != nil &&
 			pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil &&
-			!podFitsCurrentNode(pod) && podFitsAnySchedulableNode(pod) {
+			!p.podFitsCurrentNode(log, pod) && p.podFitsAnySchedulableNode(log, pod) {
 			// Pod have node affinity and can find a prefered node
-			fmt.Printf("Find prefered node. %v marked as evicted\n", pod.Name)
+			log.V(2).Info("Find prefered node, pod marked as evicted", "pod", pod.Name)
 			evicts = append(evicts, pod)
 		} else {
 			remains = append(remains, pod)
@@ -61,89 +139,153 @@ func evictUnfitPods(pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
 	return remains, evicts
 }
 
-func podFitsCurrentNode(pod *api_v1.Pod) bool {
-	node, err := getPodNode(pod)
+func (p *Predictor) podFitsCurrentNode(log logger.Logger, pod *api_v1.Pod) bool {
+	node, err := p.getPodNode(pod)
 	if err != nil {
-		fmt.Println("Get pod node failed, skipping process this pod", pod.Name, err)
+		log.Error(err, "Get pod node failed, skipping process this pod", "pod", pod.Name)
 		return true
 	}
 	ok, err := predicates.PodMatchNodeSelector(pod, node)
 
 	if err != nil {
-		fmt.Printf("Check if pod fit Current node failed, %v\n", err)
+		log.Error(err, "Check if pod fit current node failed", "pod", pod.Name)
 		return false
 	}
 
 	if !ok {
-		fmt.Printf("Pod %v does not fit on node %v\n", pod.Name, node.Name)
+		log.V(4).Info("Pod does not fit on its node", "pod", pod.Name)
 		return false
 	}
 
-	fmt.Printf("Pod %v fits on node %v\n", pod.Name, node.Name)
+	log.V(4).Info("Pod fits on its node", "pod", pod.Name)
 	return true
 }
 
-func podFitsAnySchedulableNode(pod *api_v1.Pod) bool {
+func (p *Predictor) podFitsAnySchedulableNode(log logger.Logger, pod *api_v1.Pod) bool {
 
-	nodes, err := getOperatableNodes()
+	nodes, err := p.getOperatableNodes()
 	if err != nil {
-		fmt.Println("Get operatable nodes failed, ", err)
+		log.Error(err, "Get operatable nodes failed")
 	}
 
 	for _, node := range nodes {
+		if !isNodeSchedulable(node) {
+			continue
+		}
 		ok, err := predicates.PodMatchNodeSelector(pod, node)
 		if err != nil || !ok {
 			continue
 		}
-		if ok {
-			if isNodeSchedulable(node) {
-				fmt.Printf("Pod %v can possibly be scheduled on %v\n", pod.Name, node.Name)
-				return true
-			}
-			return false
+		if !predicates.PodToleratesNodeTaints(pod, node, predicates.IsSchedulingTaint) {
+			continue
 		}
+		log.V(4).Info("Pod can possibly be scheduled on another node", "pod", pod.Name, "target", node.Name)
+		return true
 	}
 	return false
 }
 
-func getEvictablePods(node *api_v1.Node) ([]*api_v1.Pod, error) {
-	pods, err := getPodsOnNode(node)
+func (p *Predictor) getEvictablePods(log logger.Logger, node *api_v1.Node) ([]*api_v1.Pod, error) {
+	pods, err := p.getPodsOnNode(node)
 	if err != nil {
 		return []*api_v1.Pod{}, err
 	}
 	evictablePods := make([]*api_v1.Pod, 0)
 	for _, pod := range pods {
-		if !isEvictable(pod) {
+		// Pods in other namespaces are still counted in node usage, but never evicted.
+		if !p.isNamespaceAffected(pod.Namespace) || !p.isEvictable(log, pod) {
 			continue
 		} else {
 			evictablePods = append(evictablePods, pod)
-			fmt.Println("Found pod that evictable:", pod.ObjectMeta.Name)
+			log.V(4).Info("Found pod that evictable", "pod", pod.ObjectMeta.Name)
 		}
 	}
 	return evictablePods, nil
 }
 
 // Checks if pod is evictable
-func isEvictable(pod *api_v1.Pod) bool {
+func (p *Predictor) isEvictable(log logger.Logger, pod *api_v1.Pod) bool {
+	evict, _ := p.getPodAnnotation(pod, EvictAnnotationKey)
+	if evict == "false" {
+		log.V(4).Info("Pod opted out of eviction", "pod", pod.Name)
+		return false
+	}
 	ownerRefList := ownerRef(pod)
 	if isMirrorPod(pod) ||
-		isPodWithLocalStorage(pod) ||
+		(isPodWithLocalStorage(pod) && evict != "true") ||
 		len(ownerRefList) == 0 ||
 		isDaemonsetPod(ownerRefList) ||
-		isCriticalPod(pod) {
+		(isJobPod(ownerRefList) && !p.conf.Rules.EvictJobPods) ||
+		isCriticalPod(pod) ||
+		podPriority(pod) > p.conf.Rules.MaxPriority ||
+		(p.isSingleReplicaPod(pod) && !p.conf.Rules.HardEviction) ||
+		p.isFailedWorkloadPod(log, pod) {
 		return false
 	}
+	return p.isPodOldEnough(log, pod)
+}
+
+// SetFailedWorkloads sets the keys of the workloads that failed to recover, so
+// that the next deschedule terms leave their pods alone until they are ready.
+func (p *Predictor) SetFailedWorkloads(keys []string) {
+	p.failedWorkloads = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		p.failedWorkloads[key] = true
+	}
+}
+
+// isFailedWorkloadPod checks if the pod belongs to a workload that failed to
+// recover and is still not ready, the workloads ready again are forgotten.
+func (p *Predictor) isFailedWorkloadPod(log logger.Logger, pod *api_v1.Pod) bool {
+	if len(p.failedWorkloads) == 0 {
+		return false
+	}
+	w := p.getPodWorkload(pod)
+	if w == nil || !p.failedWorkloads[w.Key()] {
+		return false
+	}
+	if w.IsReady() {
+		delete(p.failedWorkloads, w.Key())
+		return false
+	}
+	log.V(2).Info("Pod left alone, its workload failed to recover and is not ready yet", "pod", pod.Namespace+"/"+pod.Name, "workload", w.Key())
 	return true
 }
 
+// isNamespaceAffected checks if the namespace is selected by spec.rules.affectNamespaces
+// and not excluded by spec.rules.excludeNamespaces.
+func (p *Predictor) isNamespaceAffected(namespace string) bool {
+	for _, ns := range p.conf.Rules.ExcludeNamespaces {
+		if ns == namespace {
+			return false
+		}
+	}
+	if len(p.conf.Rules.AffectNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range p.conf.Rules.AffectNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// isSingleReplicaPod checks if the pod is the only replica of its workload,
+// pods of the workloads that descheduler don't know are taken as so.
+func (p *Predictor) isSingleReplicaPod(pod *api_v1.Pod) bool {
+	w := p.getPodWorkload(pod)
+	return w == nil || w.Replicas <= 1
+}
+
 // ownerRef returns the ownerRefList for the pod.
 func ownerRef(pod *api_v1.Pod) []v1.OwnerReference {
 	return pod.ObjectMeta.GetOwnerReferences()
 }
 
-func isReplicaSetPod(ownerRefList []v1.OwnerReference) bool {
+func isJobPod(ownerRefList []v1.OwnerReference) bool {
 	for _, ownerRef := range ownerRefList {
-		if ownerRef.Kind == "ReplicaSet" {
+		if ownerRef.Kind == "Job" {
 			return true
 		}
 	}
@@ -186,8 +328,8 @@ func MetaPodNodeIndexFunc(obj interface{}) ([]string, error) {
 	return []string{meta.(*api_v1.Pod).Spec.NodeName}, nil
 }
 
-func getPodsOnNode(node *api_v1.Node) ([]*api_v1.Pod, error) {
-	pods, err := indexers.podIndexer.ByIndex("byNode", node.ObjectMeta.Name)
+func (p *Predictor) getPodsOnNode(node *api_v1.Node) ([]*api_v1.Pod, error) {
+	pods, err := p.indexers.PodIndexer.ByIndex("byNode", node.ObjectMeta.Name)
 	if err != nil {
 		return []*api_v1.Pod{}, err
 	}
@@ -198,19 +340,45 @@ func getPodsOnNode(node *api_v1.Node) ([]*api_v1.Pod, error) {
 	return ret, nil
 }
 
-func Evict(pods []*api_v1.Pod) {
+// Evict evicts the pods and returns the ones that have been evicted.
+func (p *Predictor) Evict(log logger.Logger, pods []*api_v1.Pod) []*api_v1.Pod {
+	var evicted []*api_v1.Pod
 	for _, pod := range pods {
-		fmt.Println("Executing pod's eviction:", pod.ObjectMeta.Name)
-		evictPod(pod)
+		podLog := log.WithValues("pod", pod.Namespace+"/"+pod.Name)
+		podLog.Info("Executing pod's eviction", "dryRun", p.conf.DryRun)
+		decision := p.decisions[pod.UID]
+		if p.conf.Rules.AnnotateEvictedPods && !p.conf.DryRun {
+			if err := p.annotateEvictedPod(pod, decision); err != nil {
+				podLog.Error(err, "Failed to annotate pod, evicting it anyway")
+			}
+		}
+		ok, err := p.evictPod(pod)
+		if err != nil {
+			podLog.Error(err, "Eviction failed", "evicted", ok)
+		}
+		if ok {
+			evicted = append(evicted, pod)
+		}
+		if p.conf.DryRun {
+			continue
+		}
+		if err != nil && !ok {
+			p.recordEvictionFailure(pod, err)
+		} else if err == nil {
+			// pods already gone were not evicted by us and don't take the budget
+			p.evictionHistory.add(time.Now())
+			p.recordEviction(pod, decision)
+		}
 	}
+	return evicted
 }
 
-func evictPod(pod *api_v1.Pod) (bool, error) {
-	if conf.DryRun {
+func (p *Predictor) evictPod(pod *api_v1.Pod) (bool, error) {
+	if p.conf.DryRun {
 		return true, nil
 	}
 	deleteOptions := &v1.DeleteOptions{}
-	evictionVersion, _ := supportEviction()
+	evictionVersion, _ := p.supportEviction()
 	eviction := &policy.Eviction{
 		TypeMeta: v1.TypeMeta{
 			APIVersion: evictionVersion,
@@ -222,14 +390,19 @@ func evictPod(pod *api_v1.Pod) (bool, error) {
 		},
 		DeleteOptions: deleteOptions,
 	}
-	err := client.Policy().Evictions(eviction.Namespace).Evict(eviction)
+	err := p.client.Policy().Evictions(eviction.Namespace).Evict(eviction)
 	if err == nil {
+		metrics.Evictions.Inc(metrics.EvictionSuccess)
 		return true, nil
 	} else if apierrors.IsTooManyRequests(err) {
-		return false, fmt.Errorf("error when evicting pod (ignoring) %q: %v", pod.Name, err)
+		// The eviction API answers 429 when the eviction would violate a PodDisruptionBudget.
+		metrics.Evictions.Inc(metrics.EvictionPDBBlocked)
+		return false, fmt.Errorf("eviction of pod %q blocked by PodDisruptionBudget: %v", pod.Name, err)
 	} else if apierrors.IsNotFound(err) {
+		metrics.Evictions.Inc(metrics.EvictionNotFound)
 		return true, fmt.Errorf("pod not found when evicting %q: %v", pod.Name, err)
 	} else {
+		metrics.Evictions.Inc(metrics.EvictionError)
 		return false, err
 	}
 }
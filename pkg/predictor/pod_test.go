@@ -0,0 +1,228 @@
+package predictor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// clusterFixture is a busy node running two pods of web and one pod of api,
+// and a spared node running the other pods of them.
+func clusterFixture() []interface{} {
+	web := fake.NewReplicaSet("default", "web", 3)
+	api := fake.NewReplicaSet("default", "api", 2)
+	return []interface{}{
+		fake.NewNode("busy", "4", "8Gi", 110),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		web, api,
+		fake.SetController(fake.NewPod("default", "web-1", "busy", "1", "1Gi"), "ReplicaSet", web),
+		fake.SetController(fake.NewPod("default", "web-2", "busy", "1", "1Gi"), "ReplicaSet", web),
+		fake.SetController(fake.NewPod("default", "api-1", "busy", "1", "1Gi"), "ReplicaSet", api),
+		fake.SetController(fake.NewPod("default", "web-3", "spared", "100m", "1Gi"), "ReplicaSet", web),
+		fake.SetController(fake.NewPod("default", "api-2", "spared", "100m", "1Gi"), "ReplicaSet", api),
+	}
+}
+
+func getEvictPods(t *testing.T, p *Predictor) []*api_v1.Pod {
+	log := logger.WithValues("test", t.Name())
+	nodes, ok := p.GetBusyNodes(log)
+	if !assert.True(t, ok) {
+		return nil
+	}
+	pods, err := p.GetEvictPods(log, nodes)
+	assert.NoError(t, err)
+	return pods
+}
+
+func podNames(pods []*api_v1.Pod) []string {
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestIsFailedWorkloadPod(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	api := objs[3].(*apps_v1.ReplicaSet)
+	api.Status.ReadyReplicas = 1
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	pod := objs[len(objs)-1].(*api_v1.Pod)
+	log := logger.WithValues("test", t.Name())
+
+	assert.True(t, p.isEvictable(log, pod))
+	p.SetFailedWorkloads([]string{"ReplicaSet/default/api"})
+	assert.False(t, p.isEvictable(log, pod))
+	// The workload ready again is forgotten.
+	api.Status.ReadyReplicas = 2
+	assert.True(t, p.isEvictable(log, pod))
+	assert.Empty(t, p.failedWorkloads)
+}
+
+func TestGetEvictPods(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestPredictor(config.DefaultConfig(), clusterFixture()...)
+	pods := getEvictPods(t, p)
+	names := podNames(pods)
+	// One of the peers on the busy node is evicted first, the other one is pinned.
+	if assert.Len(t, names, 2) {
+		assert.True(t, strings.HasPrefix(names[0], "web-"), names[0])
+		assert.Equal(t, "evictWithPeerOnOneNode", p.decisions[pods[0].UID].strategy.name)
+		assert.Equal(t, "api-1", names[1])
+		assert.Equal(t, "evictWithPeer", p.decisions[pods[1].UID].strategy.name)
+		assert.Equal(t, "usage", p.decisions[pods[1].UID].nodeClass)
+	}
+}
+
+func TestGetEvictPodsMaxEvictSize(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.MaxEvictSize = 1
+	p, _ := newTestPredictor(conf, clusterFixture()...)
+	assert.Len(t, getEvictPods(t, p), 1)
+}
+
+func TestGetEvictPodsExcludedNamespace(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.ExcludeNamespaces = []string{"default"}
+	p, _ := newTestPredictor(conf, clusterFixture()...)
+	assert.Empty(t, getEvictPods(t, p))
+}
+
+func TestGetEvictPodsSingleReplica(t *testing.T) {
+	t.Parallel()
+	single := fake.NewReplicaSet("default", "single", 1)
+	objs := []interface{}{
+		fake.NewNode("busy", "4", "8Gi", 110),
+		fake.NewNode("spared", "4", "8Gi", 110),
+		single,
+		fake.SetController(fake.NewPod("default", "single-1", "busy", "3", "1Gi"), "ReplicaSet", single),
+	}
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	assert.Empty(t, getEvictPods(t, p))
+
+	// hardEviction makes the pod evictable, but no strategy marks a pod without peers.
+	conf := config.DefaultConfig()
+	conf.Rules.HardEviction = true
+	p, _ = newTestPredictor(conf, objs...)
+	assert.Empty(t, getEvictPods(t, p))
+}
+
+func TestGetEvictPodsPodDisruptionBudget(t *testing.T) {
+	t.Parallel()
+	objs := append(clusterFixture(), fake.NewPodDisruptionBudget("default", "api", "api", 0))
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	names := podNames(getEvictPods(t, p))
+	if assert.Len(t, names, 1) {
+		assert.True(t, strings.HasPrefix(names[0], "web-"), names[0])
+	}
+}
+
+func TestEvict(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.AnnotateEvictedPods = true
+	p, clientset := newTestPredictor(conf, clusterFixture()...)
+	pods := getEvictPods(t, p)
+	evicted := p.Evict(logger.WithValues("test", t.Name()), pods)
+	assert.Equal(t, podNames(pods), podNames(evicted))
+	assert.Len(t, clientset.EvictedPods(), 2)
+	assert.Contains(t, string(clientset.PodPatch("default/api-1")), EvictedAnnotationKey)
+	// Each eviction is recorded on the pod, the ReplicaSet and the node.
+	assert.Len(t, clientset.CreatedEvents(), 6)
+	// Evicted pods are gone from the cluster.
+	_, exists, _ := p.indexers.PodIndexer.GetByKey("default/api-1")
+	assert.False(t, exists)
+}
+
+func TestEvictDryRun(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.DryRun = true
+	conf.Rules.AnnotateEvictedPods = true
+	p, clientset := newTestPredictor(conf, clusterFixture()...)
+	pods := getEvictPods(t, p)
+	evicted := p.Evict(logger.WithValues("test", t.Name()), pods)
+	assert.Len(t, evicted, 2)
+	assert.Empty(t, clientset.EvictedPods())
+	assert.Empty(t, clientset.CreatedEvents())
+	assert.Nil(t, clientset.PodPatch("default/api-1"))
+}
+
+func TestEvictFailure(t *testing.T) {
+	t.Parallel()
+	p, clientset := newTestPredictor(config.DefaultConfig(), clusterFixture()...)
+	clientset.EvictError = func(eviction *policy.Eviction) error {
+		if eviction.Name == "api-1" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	pods := getEvictPods(t, p)
+	evicted := p.Evict(logger.WithValues("test", t.Name()), pods)
+	if assert.Len(t, evicted, 1) {
+		assert.NotEqual(t, "api-1", evicted[0].Name)
+	}
+	var warnings []*api_v1.Event
+	for _, event := range clientset.CreatedEvents() {
+		if event.Type == api_v1.EventTypeWarning {
+			warnings = append(warnings, event)
+		}
+	}
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, "api-1", warnings[0].InvolvedObject.Name)
+	}
+}
+
+func TestGetEvictablePodsAnnotations(t *testing.T) {
+	t.Parallel()
+	web := fake.NewReplicaSet("default", "web", 3)
+	batch := fake.NewReplicaSet("batch", "web", 3)
+	newPod := func(namespace, name string, annotations map[string]string) *api_v1.Pod {
+		owner := web
+		if namespace == "batch" {
+			owner = batch
+		}
+		pod := fake.SetController(fake.NewPod(namespace, name, "busy", "100m", "1Gi"), "ReplicaSet", owner)
+		pod.Annotations = annotations
+		return pod
+	}
+	withLocalStorage := func(pod *api_v1.Pod) *api_v1.Pod {
+		pod.Spec.Volumes = []api_v1.Volume{{Name: "cache", VolumeSource: api_v1.VolumeSource{EmptyDir: &api_v1.EmptyDirVolumeSource{}}}}
+		return pod
+	}
+	withStartTime := func(pod *api_v1.Pod, ago time.Duration) *api_v1.Pod {
+		startTime := v1.NewTime(time.Now().Add(-ago))
+		pod.Status.StartTime = &startTime
+		return pod
+	}
+	node := fake.NewNode("busy", "4", "8Gi", 110)
+	p, _ := newTestPredictor(config.DefaultConfig(),
+		node, web, batch,
+		&api_v1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "batch", Annotations: map[string]string{EvictAnnotationKey: "false"}}},
+		newPod("default", "plain", nil),
+		newPod("default", "opted-out", map[string]string{EvictAnnotationKey: "false"}),
+		withLocalStorage(newPod("default", "local-storage", nil)),
+		withLocalStorage(newPod("default", "local-storage-opted-in", map[string]string{EvictAnnotationKey: "true"})),
+		newPod("batch", "namespace-opted-out", nil),
+		newPod("batch", "opted-in", map[string]string{EvictAnnotationKey: "true"}),
+		withStartTime(newPod("default", "young", map[string]string{MinAgeAnnotationKey: "1h"}), time.Minute),
+		withStartTime(newPod("default", "old", map[string]string{MinAgeAnnotationKey: "1h"}), 2*time.Hour),
+		newPod("default", "invalid-min-age", map[string]string{MinAgeAnnotationKey: "an hour"}),
+	)
+	pods, err := p.getEvictablePods(logger.WithValues("test", t.Name()), node)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"plain", "local-storage-opted-in", "opted-in", "old"}, podNames(pods))
+}
@@ -1,69 +1,113 @@
 package predictor
 
 import (
+	"fmt"
+
 	"github.com/lentil1016/descheduler/pkg/config"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
-	lister_appv1 "k8s.io/client-go/listers/apps/v1"
 	lister_apiv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
-type indexersType struct {
-	nodeIndexer cache.Indexer
-	rsIndexer   cache.Indexer
-	podIndexer  cache.Indexer
+// Indexers are the informer indexers that predictor reads the cluster state from.
+type Indexers struct {
+	NodeIndexer cache.Indexer
+	RSIndexer   cache.Indexer
+	SSIndexer   cache.Indexer
+	RCIndexer   cache.Indexer
+	// JobIndexer is only used when spec.rules.evictJobPods is enabled
+	JobIndexer cache.Indexer
+	PodIndexer cache.Indexer
+	PDBIndexer cache.Indexer
+	// PVCIndexer and PVIndexer are optional, volume zones are not checked without them
+	PVCIndexer cache.Indexer
+	PVIndexer  cache.Indexer
+	// NamespaceIndexer is optional, the annotations of namespaces are not read without it
+	NamespaceIndexer cache.Indexer
 }
 
-var indexers indexersType
-var conf config.ConfigSpec
-var nodeLister lister_apiv1.NodeLister
-var rsLister lister_appv1.ReplicaSetLister
-var client kubernetes.Interface
+// Predictor picks the pods to evict from the cluster state in its indexers.
+// Deschedule terms are run one at a time by the handler, the state of the
+// current term is kept here without locking.
+type Predictor struct {
+	indexers      Indexers
+	conf          config.ConfigSpec
+	nodeLister    lister_apiv1.NodeLister
+	client        kubernetes.Interface
+	metricsClient MetricsClient
+	eventRecorder EventRecorder
+
+	// classes of the nodes in the current deschedule term, usage, spared or normal
+	nodeClasses map[string]string
+	// usages of the nodes in the current deschedule term
+	nodeUsages map[string]nodeUsage
+	// decisions of the pods picked in the current deschedule term
+	decisions map[k8stypes.UID]evictDecision
+	// plan of the moves in the current deschedule term
+	plan []Move
+	// evictionHistory is the time of the evictions in the past hour, shared with other predictors
+	evictionHistory *EvictionHistory
+	// failedWorkloads are the keys of the workloads that failed to recover,
+	// their pods are not evicted until they are ready again
+	failedWorkloads map[string]bool
+}
 
-func Init(nodeIndexer, rsIndexer, podIndexer cache.Indexer, clientset kubernetes.Interface) {
-	indexers = indexersType{
-		nodeIndexer: nodeIndexer,
-		rsIndexer:   rsIndexer,
-		podIndexer:  podIndexer,
+// NewPredictor creates a Predictor reading the cluster from the indexers,
+// and evicting pods, reading metrics and recording Events with the clientset.
+func NewPredictor(informerIndexers Indexers, clientset kubernetes.Interface, conf config.ConfigSpec) *Predictor {
+	return &Predictor{
+		indexers:      informerIndexers,
+		conf:          conf,
+		nodeLister:    lister_apiv1.NewNodeLister(informerIndexers.NodeIndexer),
+		client:        clientset,
+		metricsClient: NewMetricsClient(clientset),
+		eventRecorder: NewEventRecorder(clientset),
+
+		evictionHistory: NewEvictionHistory(),
+	}
+}
+
+// ValidateConfig checks if the predictor can work with the rules and triggers of the config.
+func ValidateConfig(conf config.ConfigSpec) error {
+	if _, err := labels.Parse(conf.Rules.NodeSelector); err != nil {
+		return fmt.Errorf("Please check config file. Can't parse spec.rules.nodeSelector with value %q: %v", conf.Rules.NodeSelector, err)
+	}
+	if _, err := labels.Parse(conf.Rules.ExcludeNodes.LabelSelector); err != nil {
+		return fmt.Errorf("Please check config file. Can't parse spec.rules.excludeNodes.labelSelector with value %q: %v", conf.Rules.ExcludeNodes.LabelSelector, err)
+	}
+	if _, err := newNodeScorer(conf.Triggers.Scorer); err != nil {
+		return fmt.Errorf("Please check config file. %v", err)
 	}
-	client = clientset
-	conf = config.GetConfig()
-	nodeLister = lister_apiv1.NewNodeLister(nodeIndexer)
-	rsLister = lister_appv1.NewReplicaSetLister(rsIndexer)
+	return nil
 }
 
-func scoreNode(cpuUsage, memUsage, podUsage float64) (float64, float64, float64) {
-	var usageScore, sparedScore, normalScore float64
-	usageScore, sparedScore, normalScore = scoreResource(cpuUsage,
-		(100 - conf.Triggers.MinSparedPercentage.CPU),
-		conf.Triggers.MaxSparedPercentage.CPU,
-		usageScore, sparedScore, normalScore)
-	usageScore, sparedScore, normalScore = scoreResource(memUsage,
-		(100 - conf.Triggers.MinSparedPercentage.Memory),
-		conf.Triggers.MaxSparedPercentage.Memory,
-		usageScore, sparedScore, normalScore)
-	usageScore, sparedScore, normalScore = scoreResource(podUsage,
-		(100 - conf.Triggers.MinSparedPercentage.Pod),
-		conf.Triggers.MaxSparedPercentage.Pod,
-		usageScore, sparedScore, normalScore)
-	return usageScore, sparedScore, normalScore
+// SetConfig swaps the config in, it must be called between deschedule terms.
+func (p *Predictor) SetConfig(conf config.ConfigSpec) {
+	p.conf = conf
 }
 
-func scoreResource(usage, maxUsage, maxSpared, usageScore, sparedScore, normalScore float64) (float64, float64, float64) {
-	spared := 100 - usage
-	normalScore = normalScore + usage*usage/100
-	if usage > maxUsage {
-		usageScore = usageScore + usage*usage/100
-	} else if spared > maxSpared {
-		sparedScore = sparedScore + spared*spared/100
+// scoreNode scores the node with the scorer by every resource in its usage,
+// against the thresholds of the resource.
+func (p *Predictor) scoreNode(scorer NodeScorer, usage nodeUsage) (float64, float64, float64) {
+	var resources []ResourceUsage
+	for _, name := range usage.resources() {
+		resources = append(resources, ResourceUsage{
+			Name:      name,
+			Usage:     usage[name],
+			MaxUsage:  p.maxUsage(name),
+			MaxSpared: p.maxSpared(name),
+			Weight:    p.resourceWeight(name),
+		})
 	}
-	return usageScore, sparedScore, normalScore
+	return scorer.Score(resources)
 }
 
 // SupportEviction uses Discovery API to find out if the server support eviction subresource
 // If support, it will return its groupVersion; Otherwise, it will return ""
-func supportEviction() (string, error) {
-	discoveryClient := client.Discovery()
+func (p *Predictor) supportEviction() (string, error) {
+	discoveryClient := p.client.Discovery()
 	groupList, err := discoveryClient.ServerGroups()
 	if err != nil {
 		return "", err
@@ -0,0 +1,103 @@
+package predictor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+func TestMain(m *testing.M) {
+	// Log to stderr instead of files, go test shows the logs of failed tests.
+	klog.InitFlags(nil)
+	os.Exit(m.Run())
+}
+
+// newTestPredictor creates a Predictor reading the objects from in-memory indexers.
+func newTestPredictor(conf config.ConfigSpec, objs ...interface{}) (*Predictor, *fake.Clientset) {
+	byNamespace := cache.Indexers{"byNamespace": cache.MetaNamespaceIndexFunc}
+	indexers := Indexers{
+		NodeIndexer: fake.NewIndexer(cache.Indexers{}),
+		RSIndexer:   fake.NewIndexer(byNamespace),
+		SSIndexer:   fake.NewIndexer(byNamespace),
+		RCIndexer:   fake.NewIndexer(byNamespace),
+		JobIndexer:  fake.NewIndexer(byNamespace),
+		PodIndexer:  fake.NewIndexer(cache.Indexers{"byNode": MetaPodNodeIndexFunc}),
+		PDBIndexer:  fake.NewIndexer(byNamespace),
+		PVCIndexer:  fake.NewIndexer(byNamespace),
+		PVIndexer:   fake.NewIndexer(cache.Indexers{}),
+
+		NamespaceIndexer: fake.NewIndexer(cache.Indexers{}),
+	}
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *api_v1.Node:
+			indexers.NodeIndexer.Add(obj)
+		case *apps_v1.ReplicaSet:
+			indexers.RSIndexer.Add(obj)
+		case *api_v1.Pod:
+			indexers.PodIndexer.Add(obj)
+		case *policy.PodDisruptionBudget:
+			indexers.PDBIndexer.Add(obj)
+		case *api_v1.PersistentVolumeClaim:
+			indexers.PVCIndexer.Add(obj)
+		case *api_v1.PersistentVolume:
+			indexers.PVIndexer.Add(obj)
+		case *api_v1.Namespace:
+			indexers.NamespaceIndexer.Add(obj)
+		default:
+			panic("unsupported fixture")
+		}
+	}
+	clientset := fake.NewClientset()
+	clientset.PodIndexer = indexers.PodIndexer
+	p := NewPredictor(indexers, clientset, conf)
+	return p, clientset
+}
+
+func TestScoreNode(t *testing.T) {
+	t.Parallel()
+	p, _ := newTestPredictor(config.DefaultConfig())
+	tests := []struct {
+		name                  string
+		cpu, memory, pods     float64
+		usage, spared, normal bool
+	}{
+		{"cpu running low", 80, 40, 40, true, false, true},
+		{"highly spared", 10, 20, 5, false, true, true},
+		{"normal", 50, 50, 50, false, false, true},
+	}
+	// The built-in scorers classify the nodes alike.
+	for _, name := range []string{"squaredSum", "max", "weightedLinear"} {
+		scorer, err := newNodeScorer(config.ConfigScorer{Name: name})
+		require.NoError(t, err)
+		for _, test := range tests {
+			usageScore, sparedScore, normalScore := p.scoreNode(scorer, nodeUsage{"cpu": test.cpu, "memory": test.memory, "pod": test.pods})
+			assert.Equal(t, test.usage, usageScore != 0, name, test.name)
+			assert.Equal(t, test.spared, sparedScore != 0, name, test.name)
+			assert.Equal(t, test.normal, normalScore != 0, name, test.name)
+		}
+	}
+}
+
+func TestScoreNodeResourceWeights(t *testing.T) {
+	t.Parallel()
+	usage := nodeUsage{"cpu": 80, "memory": 40, "pod": 40}
+	p, _ := newTestPredictor(config.DefaultConfig())
+	scorer := builtinScorer{aggregate: sumSquares}
+	usageScore, _, _ := p.scoreNode(scorer, usage)
+
+	conf := config.DefaultConfig()
+	conf.Triggers.ResourceWeights = config.ConfigResourceWeight{"cpu": 2}
+	p, _ = newTestPredictor(conf)
+	weightedScore, _, _ := p.scoreNode(scorer, usage)
+	assert.Equal(t, 2*usageScore, weightedScore)
+}
@@ -0,0 +1,94 @@
+package predictor
+
+import (
+	"sort"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// qosRanks orders the QoS classes, the pods of a lower ranked class are evicted first.
+var qosRanks = map[api_v1.PodQOSClass]int{
+	api_v1.PodQOSBestEffort: 0,
+	api_v1.PodQOSBurstable:  1,
+	api_v1.PodQOSGuaranteed: 2,
+}
+
+// rankedPods are the pods marked as evicted along with the strategies marked them.
+type rankedPods struct {
+	pods       []*api_v1.Pod
+	strategies []evictStrategy
+}
+
+func (r rankedPods) Len() int { return len(r.pods) }
+
+func (r rankedPods) Swap(i, j int) {
+	r.pods[i], r.pods[j] = r.pods[j], r.pods[i]
+	r.strategies[i], r.strategies[j] = r.strategies[j], r.strategies[i]
+}
+
+// Less puts the pods of lower priority first, then the ones of lower QoS class,
+// then the younger ones, like ReplicaSets pick the pods to delete when scaling down.
+func (r rankedPods) Less(i, j int) bool {
+	a, b := r.pods[i], r.pods[j]
+	if pa, pb := podPriority(a), podPriority(b); pa != pb {
+		return pa < pb
+	}
+	if qa, qb := qosRanks[getPodQOS(a)], qosRanks[getPodQOS(b)]; qa != qb {
+		return qa < qb
+	}
+	return podStartTime(b).Before(podStartTime(a))
+}
+
+// sortRankedPods sorts the pods in place, pods tied keep the order of the strategies marked them.
+func sortRankedPods(pods []*api_v1.Pod, strategies []evictStrategy) {
+	sort.Stable(rankedPods{pods, strategies})
+}
+
+func podPriority(pod *api_v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+func podStartTime(pod *api_v1.Pod) time.Time {
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// getPodQOS returns the QoS class of the pod, computed from the requests and
+// limits of its containers if the status doesn't have it yet.
+func getPodQOS(pod *api_v1.Pod) api_v1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+	guaranteed := true
+	bestEffort := true
+	// A new slice, appending to InitContainers may write into the pod of the informer.
+	containers := make([]api_v1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, name := range []api_v1.ResourceName{api_v1.ResourceCPU, api_v1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+			if hasRequest && !request.IsZero() || hasLimit && !limit.IsZero() {
+				bestEffort = false
+			}
+			// Requests default to limits.
+			if !hasLimit || limit.IsZero() || hasRequest && request.Cmp(limit) != 0 {
+				guaranteed = false
+			}
+		}
+	}
+	if bestEffort {
+		return api_v1.PodQOSBestEffort
+	}
+	if guaranteed {
+		return api_v1.PodQOSGuaranteed
+	}
+	return api_v1.PodQOSBurstable
+}
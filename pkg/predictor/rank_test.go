@@ -0,0 +1,89 @@
+package predictor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRankedPod(name string, priority int32, qos api_v1.PodQOSClass, age time.Duration) *api_v1.Pod {
+	pod := fake.NewPod("default", name, "busy", "100m", "1Gi")
+	pod.Spec.Priority = &priority
+	pod.Status.QOSClass = qos
+	pod.CreationTimestamp = v1.NewTime(time.Now().Add(-age))
+	return pod
+}
+
+func TestSortRankedPods(t *testing.T) {
+	t.Parallel()
+	pods := []*api_v1.Pod{
+		newRankedPod("payment", 1000, api_v1.PodQOSGuaranteed, time.Hour),
+		newRankedPod("web-guaranteed", 0, api_v1.PodQOSGuaranteed, time.Hour),
+		newRankedPod("web-old", 0, api_v1.PodQOSBurstable, 2*time.Hour),
+		newRankedPod("web-young", 0, api_v1.PodQOSBurstable, time.Minute),
+		newRankedPod("batch", -10, api_v1.PodQOSGuaranteed, time.Hour),
+		newRankedPod("web-best-effort", 0, api_v1.PodQOSBestEffort, time.Hour),
+	}
+	strategies := make([]evictStrategy, len(pods))
+	for i, pod := range pods {
+		strategies[i].name = pod.Name
+	}
+	sortRankedPods(pods, strategies)
+	expected := []string{"batch", "web-best-effort", "web-young", "web-old", "web-guaranteed", "payment"}
+	assert.Equal(t, expected, podNames(pods))
+	for i, pod := range pods {
+		assert.Equal(t, pod.Name, strategies[i].name)
+	}
+}
+
+func TestGetPodQOS(t *testing.T) {
+	t.Parallel()
+	burstable := fake.NewPod("default", "burstable", "busy", "100m", "1Gi")
+	bestEffort := fake.NewPod("default", "best-effort", "busy", "0", "0")
+	bestEffort.Spec.Containers[0].Resources.Requests = nil
+	guaranteed := fake.NewPod("default", "guaranteed", "busy", "100m", "1Gi")
+	guaranteed.Spec.Containers[0].Resources.Limits = api_v1.ResourceList{
+		api_v1.ResourceCPU:    resource.MustParse("100m"),
+		api_v1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	assert.Equal(t, api_v1.PodQOSBurstable, getPodQOS(burstable))
+	assert.Equal(t, api_v1.PodQOSBestEffort, getPodQOS(bestEffort))
+	assert.Equal(t, api_v1.PodQOSGuaranteed, getPodQOS(guaranteed))
+
+	// The init containers with room left in their slice are not written to.
+	guaranteed.Spec.InitContainers = make([]api_v1.Container, 1, 2)
+	guaranteed.Spec.InitContainers[0] = guaranteed.Spec.Containers[0]
+	assert.Equal(t, api_v1.PodQOSGuaranteed, getPodQOS(guaranteed))
+	assert.Empty(t, guaranteed.Spec.InitContainers[:2][1].Name)
+}
+
+func TestGetEvictablePodsMaxPriority(t *testing.T) {
+	t.Parallel()
+	web := fake.NewReplicaSet("default", "web", 3)
+	node := fake.NewNode("busy", "4", "8Gi", 110)
+	newPod := func(name string, priority int32) *api_v1.Pod {
+		pod := fake.SetController(fake.NewPod("default", name, "busy", "100m", "1Gi"), "ReplicaSet", web)
+		pod.Spec.Priority = &priority
+		return pod
+	}
+	objs := []interface{}{node, web, newPod("low", 0), newPod("high", 1000), newPod("system", 2000000000)}
+
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	pods, err := p.getEvictablePods(logger.WithValues("test", t.Name()), node)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"low", "high"}, podNames(pods))
+
+	conf := config.DefaultConfig()
+	conf.Rules.MaxPriority = 100
+	p.SetConfig(conf)
+	pods, err = p.getEvictablePods(logger.WithValues("test", t.Name()), node)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"low"}, podNames(pods))
+}
@@ -0,0 +1,139 @@
+package predictor
+
+import (
+	"sort"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1_resource "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+// nodeUsage is the percentages of the resources of a node in use, by the resource
+// names of spec.triggers.minSparedPercentage and maxSparedPercentage. Resources
+// the node doesn't have are missing, the node is scored by the other ones.
+type nodeUsage map[string]float64
+
+func (u nodeUsage) resources() []string {
+	names := make([]string, 0, len(u))
+	for name := range u {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addUsage returns the sum of the usages as a new one.
+func addUsage(usage, other nodeUsage) nodeUsage {
+	sum := make(nodeUsage, len(usage))
+	for name, value := range usage {
+		sum[name] = value
+	}
+	for name, value := range other {
+		sum[name] += value
+	}
+	return sum
+}
+
+// subtractUsage returns the usage with the other one subtracted as a new one.
+func subtractUsage(usage, other nodeUsage) nodeUsage {
+	negative := make(nodeUsage, len(other))
+	for name, value := range other {
+		negative[name] = -value
+	}
+	return addUsage(usage, negative)
+}
+
+// getNodeResources returns the allocatable of the resources the node has, among
+// the ones in the thresholds, by their resource names there.
+func (p *Predictor) getNodeResources(node *api_v1.Node) map[string]resource.Quantity {
+	capacity := getNodeCapacity(node)
+	resources := make(map[string]resource.Quantity)
+	for _, name := range config.Resources(p.conf.Triggers.MinSparedPercentage, p.conf.Triggers.MaxSparedPercentage) {
+		allocatable, ok := capacity[resourceName(name)]
+		if ok && !allocatable.IsZero() {
+			resources[name] = allocatable
+		}
+	}
+	return resources
+}
+
+// getEmptyUsage returns the usage of the node without any pod.
+func (p *Predictor) getEmptyUsage(node *api_v1.Node) nodeUsage {
+	usage := nodeUsage{}
+	for name := range p.getNodeResources(node) {
+		usage[name] = 0
+	}
+	return usage
+}
+
+// getPodUsage returns the percentages of the resources of the node the pod requests.
+func (p *Predictor) getPodUsage(node *api_v1.Node, pod *api_v1.Pod) nodeUsage {
+	requests, _ := v1_resource.PodRequestsAndLimits(pod)
+	usage := nodeUsage{}
+	for name, allocatable := range p.getNodeResources(node) {
+		if name == config.ResourcePod {
+			usage[name] = 100 / float64(allocatable.Value())
+		} else {
+			usage[name] = percentageOf(requests[resourceName(name)], allocatable)
+		}
+	}
+	return usage
+}
+
+// getMissingResource returns a resource in the thresholds that the pod requests
+// and the node doesn't have. The projected usage of the node leaves out the
+// resources the node doesn't have, so such a node never fits the pod.
+func (p *Predictor) getMissingResource(node *api_v1.Node, pod *api_v1.Pod) (string, bool) {
+	requests, _ := v1_resource.PodRequestsAndLimits(pod)
+	resources := p.getNodeResources(node)
+	for _, name := range config.Resources(p.conf.Triggers.MinSparedPercentage, p.conf.Triggers.MaxSparedPercentage) {
+		if name == config.ResourcePod {
+			continue
+		}
+		request := requests[resourceName(name)]
+		if _, ok := resources[name]; !ok && !request.IsZero() {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func resourceName(name string) api_v1.ResourceName {
+	if name == config.ResourcePod {
+		return api_v1.ResourcePods
+	}
+	return api_v1.ResourceName(name)
+}
+
+func percentageOf(quantity, capacity resource.Quantity) float64 {
+	if capacity.IsZero() {
+		return 0
+	}
+	return float64(quantity.MilliValue()) * 100 / float64(capacity.MilliValue())
+}
+
+// maxUsage returns the usage above which the resource is running low, the
+// 100 - minSparedPercentage line. Resources missing there never run low.
+func (p *Predictor) maxUsage(name string) float64 {
+	if minSpared, ok := p.conf.Triggers.MinSparedPercentage[name]; ok {
+		return 100 - minSpared
+	}
+	return 100
+}
+
+// maxSpared returns the spared percentage above which the resource is highly
+// spared. Resources missing in maxSparedPercentage are never highly spared.
+func (p *Predictor) maxSpared(name string) float64 {
+	if maxSpared, ok := p.conf.Triggers.MaxSparedPercentage[name]; ok {
+		return maxSpared
+	}
+	return 100
+}
+
+func (p *Predictor) resourceWeight(name string) float64 {
+	if weight, ok := p.conf.Triggers.ResourceWeights[name]; ok {
+		return weight
+	}
+	return 1
+}
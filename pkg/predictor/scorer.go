@@ -0,0 +1,175 @@
+package predictor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+)
+
+// ResourceUsage is the usage of a resource on a node, with the thresholds and
+// the weight of the resource in spec.triggers.
+type ResourceUsage struct {
+	Name string
+	// Usage is the percentage of the resource in use.
+	Usage float64
+	// MaxUsage is the usage above which the resource is running low.
+	MaxUsage float64
+	// MaxSpared is the spared percentage above which the resource is highly spared.
+	MaxSpared float64
+	// Weight is the weight of the resource in spec.triggers.resourceWeights.
+	Weight float64
+}
+
+// Spared returns the percentage of the resource not in use.
+func (r ResourceUsage) Spared() float64 {
+	return 100 - r.Usage
+}
+
+// NodeScorer scores a node by the usages of its resources, sorted by name.
+// A non-zero usage score marks the node busy, otherwise a non-zero spared
+// score marks it highly spared, otherwise it is a normal node. The nodes of
+// each class are ranked by the usage, spared and normal score, higher first.
+type NodeScorer interface {
+	Score(resources []ResourceUsage) (usage, spared, normal float64)
+}
+
+// NodeScorerFunc is a function serving as a NodeScorer.
+type NodeScorerFunc func(resources []ResourceUsage) (usage, spared, normal float64)
+
+// Score calls f(resources).
+func (f NodeScorerFunc) Score(resources []ResourceUsage) (float64, float64, float64) {
+	return f(resources)
+}
+
+// NodeScorerFactory creates a NodeScorer with the params of spec.triggers.scorer,
+// it returns an error if the params are invalid.
+type NodeScorerFactory func(params map[string]float64) (NodeScorer, error)
+
+var (
+	scorersMutex sync.RWMutex
+	scorers      = map[string]NodeScorerFactory{
+		"squaredSum":     newBuiltinScorer("squaredSum", sumSquares),
+		"max":            newBuiltinScorer("max", maxValue),
+		"weightedLinear": newBuiltinScorer("weightedLinear", sumValues),
+	}
+)
+
+// RegisterNodeScorer makes a scorer selectable by the name in spec.triggers.scorer.name.
+// Programs embedding descheduler register their scorers before loading the config.
+func RegisterNodeScorer(name string, factory NodeScorerFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("Can't register node scorer %q without a name or a factory", name)
+	}
+	scorersMutex.Lock()
+	defer scorersMutex.Unlock()
+	if _, ok := scorers[name]; ok {
+		return fmt.Errorf("Node scorer %q is already registered", name)
+	}
+	scorers[name] = factory
+	return nil
+}
+
+// NodeScorers returns the names of the registered scorers, sorted.
+func NodeScorers() []string {
+	scorersMutex.RLock()
+	defer scorersMutex.RUnlock()
+	names := make([]string, 0, len(scorers))
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newNodeScorer creates the scorer selected by the config.
+func newNodeScorer(conf config.ConfigScorer) (NodeScorer, error) {
+	scorersMutex.RLock()
+	factory, ok := scorers[conf.Name]
+	scorersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("spec.triggers.scorer.name with value %q is not one of the registered scorers %v", conf.Name, NodeScorers())
+	}
+	scorer, err := factory(conf.Params)
+	if err != nil {
+		return nil, fmt.Errorf("spec.triggers.scorer.params of scorer %q are invalid: %v", conf.Name, err)
+	}
+	return scorer, nil
+}
+
+// paramRelative is the param of the built-in scorers, 1 scores the resources
+// running low or highly spared by how far they are over their lines, instead of
+// by their percentages.
+const paramRelative = "relative"
+
+// aggregate adds the weighted value of a resource to the total of the node.
+type aggregate func(total, weight, value float64) float64
+
+// sumSquares sums the weighted squares of the percentages, so that a resource
+// far over its line weighs more than a few resources slightly over.
+func sumSquares(total, weight, value float64) float64 {
+	return total + weight*value*value/100
+}
+
+// maxValue scores the node by its single most weighted percentage.
+func maxValue(total, weight, value float64) float64 {
+	return maxFloat(total, weight*value)
+}
+
+// sumValues sums the weighted percentages.
+func sumValues(total, weight, value float64) float64 {
+	return total + weight*value
+}
+
+// builtinScorer scores the resources running low into the usage score, the
+// resources highly spared into the spared score, and every resource into the
+// normal score, all by the same aggregate.
+type builtinScorer struct {
+	aggregate aggregate
+	relative  bool
+}
+
+func (s builtinScorer) Score(resources []ResourceUsage) (usage, spared, normal float64) {
+	for _, r := range resources {
+		normal = s.aggregate(normal, r.Weight, r.Usage)
+		if r.Usage > r.MaxUsage {
+			usage = s.aggregate(usage, r.Weight, s.value(r.Usage, r.MaxUsage))
+		} else if r.Spared() > r.MaxSpared {
+			spared = s.aggregate(spared, r.Weight, s.value(r.Spared(), r.MaxSpared))
+		}
+	}
+	return usage, spared, normal
+}
+
+// value returns the percentage to score, or how far it is over the line if relative.
+func (s builtinScorer) value(percentage, line float64) float64 {
+	if s.relative {
+		return percentage - line
+	}
+	return percentage
+}
+
+// newBuiltinScorer creates the factory of a built-in scorer, taking the relative param.
+func newBuiltinScorer(name string, a aggregate) NodeScorerFactory {
+	return func(params map[string]float64) (NodeScorer, error) {
+		s := builtinScorer{aggregate: a}
+		for param, value := range params {
+			if param != paramRelative {
+				return nil, fmt.Errorf("scorer %v takes only the param %v, got %q", name, paramRelative, param)
+			}
+			if value != 0 && value != 1 {
+				return nil, fmt.Errorf("param %v with value %v is neither 0 nor 1", paramRelative, value)
+			}
+			s.relative = value == 1
+		}
+		return s, nil
+	}
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,167 @@
+package predictor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinScorers(t *testing.T) {
+	t.Parallel()
+	resources := []ResourceUsage{
+		{Name: "cpu", Usage: 90, MaxUsage: 70, MaxSpared: 70, Weight: 1},
+		{Name: "memory", Usage: 80, MaxUsage: 70, MaxSpared: 70, Weight: 2},
+		{Name: "pod", Usage: 10, MaxUsage: 70, MaxSpared: 70, Weight: 1},
+	}
+	tests := []struct {
+		name                  string
+		relative              float64
+		usage, spared, normal float64
+	}{
+		{"squaredSum", 0, 81 + 2*64, 81, 81 + 2*64 + 1},
+		{"max", 0, 160, 90, 160},
+		{"weightedLinear", 0, 90 + 2*80, 90, 90 + 2*80 + 10},
+		// Relative scores count only how far cpu and memory are over 70%, and pod over 70% spared.
+		{"squaredSum", 1, 4 + 2*1, 4, 81 + 2*64 + 1},
+		{"max", 1, 20, 20, 160},
+		{"weightedLinear", 1, 20 + 2*10, 20, 90 + 2*80 + 10},
+	}
+	for _, test := range tests {
+		scorer, err := newNodeScorer(config.ConfigScorer{Name: test.name, Params: map[string]float64{"relative": test.relative}})
+		require.NoError(t, err, test.name)
+		usage, spared, normal := scorer.Score(resources)
+		assert.InDelta(t, test.usage, usage, 1e-9, test.name, test.relative)
+		assert.InDelta(t, test.spared, spared, 1e-9, test.name, test.relative)
+		assert.InDelta(t, test.normal, normal, 1e-9, test.name, test.relative)
+	}
+
+	_, err := newNodeScorer(config.ConfigScorer{Name: "max", Params: map[string]float64{"power": 2}})
+	assert.EqualError(t, err, `spec.triggers.scorer.params of scorer "max" are invalid: scorer max takes only the param relative, got "power"`)
+	_, err = newNodeScorer(config.ConfigScorer{Name: "max", Params: map[string]float64{"relative": 2}})
+	assert.EqualError(t, err, `spec.triggers.scorer.params of scorer "max" are invalid: param relative with value 2 is neither 0 nor 1`)
+}
+
+// Every built-in scorer is configurable from the config file, and finds the
+// node over its line busy with or without the relative param.
+func TestBuiltinScorersFromConfig(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"squaredSum", "max", "weightedLinear"} {
+		for _, relative := range []string{"0", "1"} {
+			conf, err := config.Load([]byte(`
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        scorer:
+            name: ` + name + `
+            params:
+                relative: ` + relative + `
+`))
+			require.NoError(t, err, name)
+			require.NoError(t, ValidateConfig(conf), name)
+			p, _ := newTestPredictor(conf,
+				fake.NewNode("a", "10", "8Gi", 110),
+				fake.NewNode("b", "10", "8Gi", 110),
+				fake.NewPod("default", "a", "a", "9", "1Gi"),
+			)
+			nodes, ok := p.GetBusyNodes(logger.WithValues())
+			assert.True(t, ok, name, relative)
+			if assert.Len(t, nodes, 1, name, relative) {
+				assert.Equal(t, "a", nodes[0].Name, name, relative)
+			}
+		}
+
+		conf, err := config.Load([]byte(`
+apiVersion: descheduler.lentil1016.cn/v1alpha1
+spec:
+    triggers:
+        scorer:
+            name: ` + name + `
+            params:
+                exponent: 3
+`))
+		require.NoError(t, err, name)
+		assert.Error(t, ValidateConfig(conf), name)
+	}
+}
+
+func TestRegisterNodeScorer(t *testing.T) {
+	t.Parallel()
+	// busiestCPU marks the nodes using more cpu than the threshold param busy,
+	// and every other node spared.
+	factory := func(params map[string]float64) (NodeScorer, error) {
+		threshold, ok := params["threshold"]
+		if !ok {
+			return nil, errors.New("threshold is required")
+		}
+		return NodeScorerFunc(func(resources []ResourceUsage) (float64, float64, float64) {
+			for _, r := range resources {
+				if r.Name == "cpu" && r.Usage > threshold {
+					return r.Usage, 0, r.Usage
+				}
+			}
+			return 0, 1, 0
+		}), nil
+	}
+	require.NoError(t, RegisterNodeScorer("busiestCPU", factory))
+	assert.EqualError(t, RegisterNodeScorer("busiestCPU", factory), `Node scorer "busiestCPU" is already registered`)
+	assert.Error(t, RegisterNodeScorer("", factory))
+	assert.Contains(t, NodeScorers(), "busiestCPU")
+
+	conf := config.DefaultConfig()
+	conf.Triggers.Scorer = config.ConfigScorer{Name: "busiestCPU"}
+	assert.EqualError(t, ValidateConfig(conf), `Please check config file. spec.triggers.scorer.params of scorer "busiestCPU" are invalid: threshold is required`)
+	conf.Triggers.Scorer.Name = "unknown"
+	assert.Error(t, ValidateConfig(conf))
+
+	// The default scorer finds nothing busy with 40% of cpu in use.
+	conf.Triggers.Scorer = config.ConfigScorer{Name: "busiestCPU", Params: map[string]float64{"threshold": 30}}
+	require.NoError(t, ValidateConfig(conf))
+	p, _ := newTestPredictor(conf,
+		fake.NewNode("a", "10", "8Gi", 110),
+		fake.NewNode("b", "10", "8Gi", 110),
+		fake.NewPod("default", "a", "a", "4", "1Gi"),
+	)
+	nodes, ok := p.GetBusyNodes(logger.WithValues())
+	assert.True(t, ok)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "a", nodes[0].Name)
+	}
+	assert.Equal(t, map[string]string{"a": "usage", "b": "spared"}, p.nodeClasses)
+}
+
+// The busy nodes are ranked by the usage score of the scorer, squaredSum prefers
+// the node running low on two resources, max the node running lowest on one.
+func TestGetBusyNodesRankedByScorer(t *testing.T) {
+	t.Parallel()
+	objs := []interface{}{
+		fake.NewNode("cpu", "10", "10Gi", 110),
+		fake.NewNode("both", "10", "10Gi", 110),
+		fake.NewNode("spared", "10", "10Gi", 110),
+		fake.NewPod("default", "a", "cpu", "9500m", "1Gi"),
+		fake.NewPod("default", "b", "both", "8", "8Gi"),
+	}
+	tests := []struct {
+		scorer string
+		nodes  []string
+	}{
+		{"squaredSum", []string{"both", "cpu"}},
+		{"max", []string{"cpu", "both"}},
+	}
+	for _, test := range tests {
+		conf := config.DefaultConfig()
+		conf.Triggers.Scorer.Name = test.scorer
+		p, _ := newTestPredictor(conf, objs...)
+		nodes, ok := p.GetBusyNodes(logger.WithValues())
+		assert.True(t, ok, test.scorer)
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		assert.Equal(t, test.nodes, names, test.scorer)
+	}
+}
@@ -0,0 +1,85 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// Modes of picking the pods marked as evicted from the busy nodes, set by spec.rules.selectionMode.
+const (
+	// selectionByNode picks the pods of the busiest node first, then of the next one.
+	selectionByNode = "byNode"
+	// selectionFair picks the pods of the node most over its usage line each time,
+	// and stops picking from a node once it's projected to be back under the line.
+	selectionFair = "fair"
+)
+
+// nodeCandidates are the ranked pods marked as evicted on a busy node, and
+// the usage of the node projected as the picked pods are gone.
+type nodeCandidates struct {
+	node       *api_v1.Node
+	log        logger.Logger
+	pods       []*api_v1.Pod
+	strategies []evictStrategy
+	next       int
+	usage      nodeUsage
+	// overloaded is true if the node is over its usage line before any pod is picked
+	overloaded bool
+}
+
+func (p *Predictor) newNodeCandidates(log logger.Logger, node *api_v1.Node) *nodeCandidates {
+	nodeLog := log.WithValues("node", node.ObjectMeta.Name)
+	pods, err := p.getEvictablePods(nodeLog, node)
+	if err != nil {
+		nodeLog.Error(err, "Get evictable pods failed, skipping this node")
+	}
+	c := &nodeCandidates{node: node, log: nodeLog, usage: p.nodeUsages[node.ObjectMeta.Name]}
+	c.pods, c.strategies = p.rankEvictablePods(nodeLog, pods)
+	c.overloaded = p.overUsageLine(c.usage) > 0
+	return c
+}
+
+// overUsageLine returns how far the usage is over the 100 - minSparedPercentage line,
+// of the resource most over it. It's not positive if the usage is under the line.
+func (p *Predictor) overUsageLine(usage nodeUsage) float64 {
+	over := -100.0
+	for name, value := range usage {
+		if m := value - p.maxUsage(name); m > over {
+			over = m
+		}
+	}
+	return over
+}
+
+// settled checks if the node was over its usage line but is projected to be back under it.
+func (p *Predictor) settled(c *nodeCandidates) bool {
+	return c.overloaded && p.overUsageLine(c.usage) <= 0
+}
+
+// take projects the usage of the node as the pod is gone.
+func (p *Predictor) take(c *nodeCandidates, pod *api_v1.Pod) {
+	c.usage = subtractUsage(c.usage, p.getPodUsage(c.node, pod))
+}
+
+// nextCandidates returns the node to pick the next pod from, nil if there is none.
+func (p *Predictor) nextCandidates(candidates []*nodeCandidates) *nodeCandidates {
+	var next *nodeCandidates
+	for _, c := range candidates {
+		if c.next >= len(c.pods) {
+			continue
+		}
+		// Nodes back under their usage line have given enough pods in fair mode, or with
+		// spec.rules.evictOnlyNeeded enabled, while nodes never over the line are not limited.
+		if (p.conf.Rules.SelectionMode == selectionFair || p.conf.Rules.EvictOnlyNeeded) && p.settled(c) {
+			continue
+		}
+		if p.conf.Rules.SelectionMode != selectionFair {
+			return c
+		}
+		// Nodes over their usage line are picked first, the one most over it first.
+		if next == nil || p.overUsageLine(c.usage) > p.overUsageLine(next.usage) {
+			next = c
+		}
+	}
+	return next
+}
@@ -0,0 +1,63 @@
+package predictor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+// twoBusyNodesFixture is a node 90% busy on cpu and a node 75% busy on cpu,
+// with every pod on them having a peer on the spared nodes.
+func twoBusyNodesFixture() []interface{} {
+	objs := []interface{}{
+		fake.NewNode("busy-1", "4", "8Gi", 110),
+		fake.NewNode("busy-2", "4", "8Gi", 110),
+		fake.NewNode("spared-1", "8", "16Gi", 110),
+		fake.NewNode("spared-2", "8", "16Gi", 110),
+	}
+	addPods := func(node string, count int, cpu string) {
+		for i := 0; i < count; i++ {
+			rs := fake.NewReplicaSet("default", fmt.Sprintf("%v-%v", node, i), 2)
+			objs = append(objs, rs,
+				fake.SetController(fake.NewPod("default", rs.Name+"-a", node, cpu, "1Gi"), "ReplicaSet", rs),
+				fake.SetController(fake.NewPod("default", rs.Name+"-b", "spared-1", "100m", "1Gi"), "ReplicaSet", rs))
+		}
+	}
+	addPods("busy-1", 4, "900m")
+	addPods("busy-2", 3, "1")
+	return objs
+}
+
+func countByNode(p *Predictor) map[string]int {
+	counts := map[string]int{}
+	for _, decision := range p.decisions {
+		counts[decision.nodeName]++
+	}
+	return counts
+}
+
+func TestGetEvictPodsByNode(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	p, _ := newTestPredictor(conf, twoBusyNodesFixture()...)
+	assert.Len(t, getEvictPods(t, p), 3)
+	// All the pods are picked from one of the busy nodes.
+	assert.Len(t, countByNode(p), 1)
+}
+
+func TestGetEvictPodsFair(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Rules.SelectionMode = "fair"
+	p, _ := newTestPredictor(conf, twoBusyNodesFixture()...)
+	// Each busy node is back under the 70% line after one of its pods is gone,
+	// the node most over the line goes first.
+	pods := getEvictPods(t, p)
+	if assert.Len(t, pods, 2) {
+		assert.Equal(t, "busy-1", pods[0].Spec.NodeName)
+	}
+	assert.Equal(t, map[string]int{"busy-1": 1, "busy-2": 1}, countByNode(p))
+}
@@ -0,0 +1,154 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/predicates"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// placementSimulator plans where the pods evicted in a deschedule term will be
+// scheduled. The resources a pod takes on its target node are reserved as soon
+// as it is planned, so that the pods planned later can't count on them.
+type placementSimulator struct {
+	nodes []*predicates.NodeInfo
+	// placed are the pods running in the cluster, the planned ones on their target
+	// nodes, and withTerms are those of them having required pod anti-affinity.
+	placed    []placedPod
+	withTerms []placedPod
+	// usages of the target nodes projected as the planned pods land
+	usages map[string]nodeUsage
+}
+
+// newPlacementSimulator takes the schedulable nodes that are not classified as
+// high usage nodes in the current deschedule term as the target nodes.
+func (p *Predictor) newPlacementSimulator(log logger.Logger) *placementSimulator {
+	s := &placementSimulator{usages: make(map[string]nodeUsage)}
+	nodes, err := p.getOperatableNodes()
+	if err != nil {
+		log.Error(err, "Get operatable nodes failed")
+	}
+	for _, node := range nodes {
+		if p.nodeClasses[node.Name] == "usage" || !isNodeSchedulable(node) {
+			continue
+		}
+		pods, err := p.getPodsOnNode(node)
+		if err != nil {
+			log.Error(err, "Get pods on node failed, skipping this target node", "node", node.Name)
+			continue
+		}
+		s.nodes = append(s.nodes, predicates.NewNodeInfo(node, pods...))
+		s.usages[node.Name] = p.nodeUsages[node.Name]
+	}
+	s.placed, s.withTerms = p.getPlacedPods(log)
+	return s
+}
+
+// findNode returns the target node the pod fits, the least allocated one after
+// taking the pod is prefered. nil is returned if the pod fits none of them.
+func (p *Predictor) findNode(log logger.Logger, s *placementSimulator, pod *api_v1.Pod) *predicates.NodeInfo {
+	volumes := p.getPodVolumes(pod)
+	var best *predicates.NodeInfo
+	var bestAllocated float64
+	for _, info := range s.nodes {
+		if info.Node.Name == pod.Spec.NodeName {
+			continue
+		}
+		if ok, err := predicates.PodMatchNodeSelector(pod, info.Node); err != nil || !ok {
+			continue
+		}
+		if !predicates.PodToleratesNodeTaints(pod, info.Node, predicates.IsSchedulingTaint) ||
+			!predicates.PodFitsResources(pod, info) ||
+			!predicates.PodFitsHostPorts(pod, info) ||
+			!predicates.PodFitsVolumeZone(info.Node, volumes) ||
+			s.violatesAntiAffinity(pod, info.Node) {
+			continue
+		}
+		if name, ok := p.getMissingResource(info.Node, pod); ok {
+			log.V(4).Info("Node lacks a resource requested by pod", "pod", pod.Name, "node", info.Node.Name, "resource", name)
+			continue
+		}
+		// A node turned busy by the pods it takes would be descheduled the next term.
+		if p.overUsageLine(addUsage(s.usages[info.Node.Name], p.getPodUsage(info.Node, pod))) > 0 {
+			continue
+		}
+		allocated := allocatedAfter(info, pod)
+		if best == nil || allocated < bestAllocated {
+			best, bestAllocated = info, allocated
+		}
+	}
+	if best != nil {
+		log.V(4).Info("Pod can possibly be scheduled on another node", "pod", pod.Name, "target", best.Node.Name)
+	}
+	return best
+}
+
+// reserve takes the resources of the pod on the target node.
+func (p *Predictor) reserve(s *placementSimulator, pod *api_v1.Pod, target *predicates.NodeInfo) {
+	target.AddPod(pod)
+	s.usages[target.Node.Name] = addUsage(s.usages[target.Node.Name], p.getPodUsage(target.Node, pod))
+	for _, list := range [][]placedPod{s.placed, s.withTerms} {
+		for i := range list {
+			if list[i].pod.UID == pod.UID {
+				list[i].node = target.Node
+			}
+		}
+	}
+}
+
+func (s *placementSimulator) violatesAntiAffinity(pod *api_v1.Pod, node *api_v1.Node) bool {
+	others := s.withTerms
+	if predicates.HasPodAntiAffinityTerms(pod) {
+		others = s.placed
+	}
+	for _, other := range others {
+		if other.pod.UID != pod.UID && predicates.PodsViolateAntiAffinity(pod, node, other.pod, other.node) {
+			return true
+		}
+	}
+	return false
+}
+
+// allocatedAfter returns the larger share of cpu and memory allocated on the node after taking the pod.
+func allocatedAfter(info *predicates.NodeInfo, pod *api_v1.Pod) float64 {
+	after := predicates.NewNodeInfo(info.Node, pod)
+	allocatableMilliCPU, allocatableMemory, _ := info.Allocatable()
+	var cpu, memory float64
+	if allocatableMilliCPU > 0 {
+		cpu = float64(info.RequestedMilliCPU+after.RequestedMilliCPU) / float64(allocatableMilliCPU)
+	}
+	if allocatableMemory > 0 {
+		memory = float64(info.RequestedMemory+after.RequestedMemory) / float64(allocatableMemory)
+	}
+	if cpu > memory {
+		return cpu
+	}
+	return memory
+}
+
+// getPodVolumes returns the persistent volumes bound to the claims of the pod.
+func (p *Predictor) getPodVolumes(pod *api_v1.Pod) []*api_v1.PersistentVolume {
+	if p.indexers.PVCIndexer == nil || p.indexers.PVIndexer == nil {
+		return nil
+	}
+	var volumes []*api_v1.PersistentVolume
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		obj, ok, err := p.indexers.PVCIndexer.GetByKey(pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName)
+		if err != nil || !ok {
+			continue
+		}
+		pvc := obj.(*api_v1.PersistentVolumeClaim)
+		if pvc.Spec.VolumeName == "" {
+			// Claims not bound yet are bound to a volume where the pod is scheduled.
+			continue
+		}
+		obj, ok, err = p.indexers.PVIndexer.GetByKey(pvc.Spec.VolumeName)
+		if err != nil || !ok {
+			continue
+		}
+		volumes = append(volumes, obj.(*api_v1.PersistentVolume))
+	}
+	return volumes
+}
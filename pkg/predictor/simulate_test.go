@@ -0,0 +1,135 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetEvictPodsReservesTargetResources(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	// The spared node can take only one of the pods on the busy node
+	// without going over its usage line.
+	objs[1] = fake.NewNode("spared", "3", "8Gi", 110)
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	assert.Len(t, getEvictPods(t, p), 1)
+}
+
+func TestGetEvictPodsHostPort(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	for _, obj := range objs {
+		if pod, ok := obj.(*api_v1.Pod); ok && pod.Name != "api-1" {
+			pod.Spec.Containers[0].Ports = []api_v1.ContainerPort{{HostPort: 8080, Protocol: api_v1.ProtocolTCP}}
+		}
+	}
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	assert.Equal(t, []string{"api-1"}, podNames(getEvictPods(t, p)))
+}
+
+// Pods requesting a resource the spared node doesn't have are left on the busy node.
+func TestGetEvictPodsExtendedResources(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	objs[0].(*api_v1.Node).Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+	for _, obj := range objs {
+		if pod, ok := obj.(*api_v1.Pod); ok && pod.Spec.NodeName == "busy" && pod.Name != "api-1" {
+			pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+		}
+	}
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	assert.Equal(t, []string{"api-1"}, podNames(getEvictPods(t, p)))
+	for _, move := range p.Plan() {
+		assert.Equal(t, "api-1", move.Pod.Name)
+	}
+}
+
+// A node lacking a resource of the thresholds isn't projected to use it, it must
+// still refuse the pods requesting the resource.
+func TestGetMissingResource(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Triggers.MinSparedPercentage["nvidia.com/gpu"] = 10
+	p, _ := newTestPredictor(conf)
+	gpu := fake.NewNode("gpu", "4", "8Gi", 110)
+	gpu.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("4")
+	plain := fake.NewNode("plain", "4", "8Gi", 110)
+	pod := fake.NewPod("default", "a", "gpu", "100m", "1Gi")
+	pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+
+	_, ok := p.getMissingResource(gpu, pod)
+	assert.False(t, ok)
+	name, ok := p.getMissingResource(plain, pod)
+	assert.True(t, ok)
+	assert.Equal(t, "nvidia.com/gpu", name)
+	_, ok = p.getMissingResource(plain, fake.NewPod("default", "b", "gpu", "100m", "1Gi"))
+	assert.False(t, ok)
+	assert.NotContains(t, addUsage(p.getEmptyUsage(plain), p.getPodUsage(plain, pod)), "nvidia.com/gpu")
+}
+
+func TestGetEvictPodsMissingResourceThreshold(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	objs[0].(*api_v1.Node).Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+	for _, obj := range objs {
+		if pod, ok := obj.(*api_v1.Pod); ok && pod.Spec.NodeName == "busy" && pod.Name != "api-1" {
+			pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+		}
+	}
+	conf := config.DefaultConfig()
+	conf.Triggers.MinSparedPercentage["nvidia.com/gpu"] = 10
+	p, _ := newTestPredictor(conf, objs...)
+	assert.Equal(t, []string{"api-1"}, podNames(getEvictPods(t, p)))
+}
+
+func TestGetEvictPodsVolumeZone(t *testing.T) {
+	t.Parallel()
+	objs := clusterFixture()
+	objs[0].(*api_v1.Node).Labels[api_v1.LabelZoneFailureDomain] = "zone-a"
+	objs[1].(*api_v1.Node).Labels[api_v1.LabelZoneFailureDomain] = "zone-b"
+	for _, obj := range objs {
+		if pod, ok := obj.(*api_v1.Pod); ok && pod.Name == "api-1" {
+			pod.Spec.Volumes = []api_v1.Volume{{
+				Name: "data",
+				VolumeSource: api_v1.VolumeSource{
+					PersistentVolumeClaim: &api_v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+				},
+			}}
+		}
+	}
+	objs = append(objs,
+		&api_v1.PersistentVolumeClaim{
+			ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "data"},
+			Spec:       api_v1.PersistentVolumeClaimSpec{VolumeName: "pv-data"},
+		},
+		&api_v1.PersistentVolume{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "pv-data",
+				Labels: map[string]string{api_v1.LabelZoneFailureDomain: "zone-a"},
+			},
+		})
+	p, _ := newTestPredictor(config.DefaultConfig(), objs...)
+	for _, name := range podNames(getEvictPods(t, p)) {
+		assert.NotEqual(t, "api-1", name)
+	}
+}
+
+func TestPodFitsAnySchedulableNode(t *testing.T) {
+	t.Parallel()
+	cordoned := fake.NewNode("cordoned", "4", "8Gi", 110)
+	cordoned.Spec.Unschedulable = true
+	p, _ := newTestPredictor(config.DefaultConfig(), cordoned, fake.NewNode("ready", "4", "8Gi", 110))
+	pod := fake.NewPod("default", "web-1", "busy", "1", "1Gi")
+	// An unschedulable node matching the pod doesn't hide the other nodes.
+	assert.True(t, p.podFitsAnySchedulableNode(logger.WithValues("test", t.Name()), pod))
+
+	p, _ = newTestPredictor(config.DefaultConfig(), cordoned)
+	assert.False(t, p.podFitsAnySchedulableNode(logger.WithValues("test", t.Name()), pod))
+}
@@ -0,0 +1,49 @@
+package predictor
+
+import (
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/lentil1016/descheduler/pkg/predicates"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// check if there is pod not tolerating the NoSchedule taints its node gained
+// after it landed, then mark as evicted if a node it tolerates is found
+func (p *Predictor) evictUntoleratedPods(log logger.Logger, pods []*api_v1.Pod) (remainPods, evictPods []*api_v1.Pod) {
+	var remains, evicts []*api_v1.Pod
+	for _, pod := range pods {
+		node, err := p.getPodNode(pod)
+		if err != nil {
+			log.Error(err, "Get pod node failed, skipping process this pod", "pod", pod.Name)
+			remains = append(remains, pod)
+			continue
+		}
+		if !predicates.PodToleratesNodeTaints(pod, node, p.isTaintConsidered) && p.podFitsAnySchedulableNode(log, pod) {
+			log.V(2).Info("Pod doesn't tolerate the taints of its node, pod marked as evicted", "pod", pod.Name)
+			evicts = append(evicts, pod)
+		} else {
+			remains = append(remains, pod)
+		}
+	}
+	return remains, evicts
+}
+
+// isTaintConsidered checks if pods not tolerating the taint are evicted for it.
+func (p *Predictor) isTaintConsidered(taint *api_v1.Taint) bool {
+	if taint.Effect != api_v1.TaintEffectNoSchedule {
+		return false
+	}
+	for _, key := range p.conf.Rules.IgnoredTaintKeys {
+		if key == taint.Key {
+			return false
+		}
+	}
+	if len(p.conf.Rules.TaintKeys) == 0 {
+		return true
+	}
+	for _, key := range p.conf.Rules.TaintKeys {
+		if key == taint.Key {
+			return true
+		}
+	}
+	return false
+}
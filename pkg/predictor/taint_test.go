@@ -0,0 +1,80 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestEvictUntoleratedPods(t *testing.T) {
+	t.Parallel()
+	newPods := func() []*api_v1.Pod {
+		return []*api_v1.Pod{
+			fake.NewPod("default", "plain", "a", "100m", "1Gi"),
+			fake.SetToleration(fake.NewPod("default", "tolerating", "a", "100m", "1Gi"), "gpu"),
+		}
+	}
+	tests := []struct {
+		name    string
+		conf    func(conf *config.ConfigSpec)
+		nodes   []interface{}
+		evicted []string
+	}{
+		{
+			name: "untolerated taint",
+			nodes: []interface{}{
+				fake.SetTaint(fake.NewNode("a", "4", "8Gi", 110), "gpu", api_v1.TaintEffectNoSchedule),
+				fake.NewNode("b", "4", "8Gi", 110),
+			},
+			evicted: []string{"plain"},
+		},
+		{
+			name: "no tolerated node",
+			nodes: []interface{}{
+				fake.SetTaint(fake.NewNode("a", "4", "8Gi", 110), "gpu", api_v1.TaintEffectNoSchedule),
+				fake.SetTaint(fake.NewNode("b", "4", "8Gi", 110), "maintenance", api_v1.TaintEffectNoExecute),
+			},
+			evicted: []string{},
+		},
+		{
+			name: "prefer no schedule",
+			nodes: []interface{}{
+				fake.SetTaint(fake.NewNode("a", "4", "8Gi", 110), "gpu", api_v1.TaintEffectPreferNoSchedule),
+				fake.NewNode("b", "4", "8Gi", 110),
+			},
+			evicted: []string{},
+		},
+		{
+			name: "taint keys",
+			conf: func(conf *config.ConfigSpec) { conf.Rules.TaintKeys = []string{"dedicated"} },
+			nodes: []interface{}{
+				fake.SetTaint(fake.NewNode("a", "4", "8Gi", 110), "gpu", api_v1.TaintEffectNoSchedule),
+				fake.NewNode("b", "4", "8Gi", 110),
+			},
+			evicted: []string{},
+		},
+		{
+			name: "ignored taint keys",
+			nodes: []interface{}{
+				fake.SetTaint(fake.NewNode("a", "4", "8Gi", 110), "node.kubernetes.io/unschedulable", api_v1.TaintEffectNoSchedule),
+				fake.NewNode("b", "4", "8Gi", 110),
+			},
+			evicted: []string{},
+		},
+	}
+	for _, test := range tests {
+		conf := config.DefaultConfig()
+		if test.conf != nil {
+			test.conf(&conf)
+		}
+		p, _ := newTestPredictor(conf, test.nodes...)
+		pods := newPods()
+		remains, evicts := p.evictUntoleratedPods(logger.WithValues("test", test.name), pods)
+		assert.Equal(t, test.evicted, podNames(evicts), test.name)
+		assert.Len(t, remains, len(pods)-len(evicts), test.name)
+	}
+}
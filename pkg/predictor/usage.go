@@ -0,0 +1,60 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MetricsClient reads the live resource usage of nodes, the default one reads
+// it from the metrics.k8s.io API served by metrics-server. Pod metrics are not
+// read, pods are picked and placed by their requests.
+type MetricsClient interface {
+	// GetNodeMetrics returns the cpu and memory the node is using now.
+	GetNodeMetrics(nodeName string) (api_v1.ResourceList, error)
+}
+
+// SetMetricsClient replaces the client reading the live usage of nodes.
+func (p *Predictor) SetMetricsClient(mc MetricsClient) {
+	p.metricsClient = mc
+}
+
+// nodeMetrics is a copy of NodeMetrics in k8s.io/metrics/pkg/apis/metrics/v1beta1,
+// with only the fields descheduler reads.
+type nodeMetrics struct {
+	v1.TypeMeta   `json:",inline"`
+	v1.ObjectMeta `json:"metadata,omitempty"`
+	Usage         api_v1.ResourceList `json:"usage"`
+}
+
+type apiMetricsClient struct {
+	client kubernetes.Interface
+}
+
+// NewMetricsClient creates a MetricsClient reading the metrics.k8s.io API.
+func NewMetricsClient(clientset kubernetes.Interface) MetricsClient {
+	return &apiMetricsClient{client: clientset}
+}
+
+func (mc *apiMetricsClient) GetNodeMetrics(nodeName string) (api_v1.ResourceList, error) {
+	data, err := mc.client.CoreV1().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/nodes", nodeName).
+		DoRaw()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get metrics of node %v: %v", nodeName, err)
+	}
+	var metrics nodeMetrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("Failed to decode metrics of node %v: %v", nodeName, err)
+	}
+	return metrics.Usage, nil
+}
+
+// blendUsage blends the usage percentage calculated from requests with the one
+// read from metrics, weight is the share of the metrics usage.
+func blendUsage(requestUsage, metricsUsage, weight float64) float64 {
+	return requestUsage*(1-weight) + metricsUsage*weight
+}
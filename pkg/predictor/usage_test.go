@@ -0,0 +1,84 @@
+package predictor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/fake"
+	"github.com/lentil1016/descheduler/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// fakeMetricsClient returns the same metrics for every node, or the error.
+type fakeMetricsClient struct {
+	usage api_v1.ResourceList
+	err   error
+	calls int
+}
+
+func (mc *fakeMetricsClient) GetNodeMetrics(nodeName string) (api_v1.ResourceList, error) {
+	mc.calls++
+	return mc.usage, mc.err
+}
+
+func TestGetNodeUsageMetrics(t *testing.T) {
+	t.Parallel()
+	// Requests take 25% of cpu and memory, metrics tell 75% of both in use.
+	node := fake.NewNode("node", "4", "8Gi", 100)
+	pod := fake.NewPod("default", "a", "node", "1", "2Gi")
+	metrics := api_v1.ResourceList{
+		api_v1.ResourceCPU:    resource.MustParse("3"),
+		api_v1.ResourceMemory: resource.MustParse("6Gi"),
+	}
+	tests := []struct {
+		name        string
+		weight      config.ConfigMetricsWeight
+		metrics     api_v1.ResourceList
+		err         error
+		cpu, memory float64
+		calls       int
+	}{
+		{"requests only", config.ConfigMetricsWeight{}, metrics, nil, 25, 25, 0},
+		{"blend cpu", config.ConfigMetricsWeight{CPU: 0.5}, metrics, nil, 50, 25, 1},
+		{"metrics only", config.ConfigMetricsWeight{CPU: 1, Memory: 1}, metrics, nil, 75, 75, 1},
+		{"mixed", config.ConfigMetricsWeight{CPU: 0.25, Memory: 0.75}, metrics, nil, 37.5, 62.5, 1},
+		{"metrics unavailable", config.ConfigMetricsWeight{CPU: 1, Memory: 1}, nil, errors.New("unavailable"), 25, 25, 1},
+		{"memory not reported", config.ConfigMetricsWeight{CPU: 1, Memory: 1}, api_v1.ResourceList{api_v1.ResourceCPU: resource.MustParse("3")}, nil, 75, 25, 1},
+	}
+	for _, test := range tests {
+		conf := config.DefaultConfig()
+		conf.Triggers.MetricsWeight = test.weight
+		p, _ := newTestPredictor(conf, node, pod)
+		mc := &fakeMetricsClient{usage: test.metrics, err: test.err}
+		p.SetMetricsClient(mc)
+		usage, err := p.getNodeUsage(logger.WithValues("test", t.Name()), node)
+		require.NoError(t, err, test.name)
+		assert.InDelta(t, test.cpu, usage["cpu"], 1e-9, test.name)
+		assert.InDelta(t, test.memory, usage["memory"], 1e-9, test.name)
+		assert.InDelta(t, 1, usage["pod"], 1e-9, test.name)
+		assert.Equal(t, test.calls, mc.calls, test.name)
+	}
+}
+
+// A node full of idle pods is spared by its live usage.
+func TestGetBusyNodesMetrics(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	conf.Triggers.MetricsWeight = config.ConfigMetricsWeight{CPU: 1, Memory: 1}
+	p, _ := newTestPredictor(conf,
+		fake.NewNode("idle", "4", "8Gi", 110),
+		fake.NewNode("other", "4", "8Gi", 110),
+		fake.NewPod("default", "a", "idle", "3500m", "7Gi"),
+	)
+	p.SetMetricsClient(&fakeMetricsClient{usage: api_v1.ResourceList{
+		api_v1.ResourceCPU:    resource.MustParse("100m"),
+		api_v1.ResourceMemory: resource.MustParse("100Mi"),
+	}})
+	_, ok := p.GetBusyNodes(logger.WithValues())
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{"idle": "spared", "other": "spared"}, p.nodeClasses)
+}
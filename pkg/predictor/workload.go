@@ -0,0 +1,172 @@
+package predictor
+
+import (
+	"strings"
+
+	"github.com/lentil1016/descheduler/pkg/logger"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Workload is the controller owning a pod, which creates a new pod after the
+// pod is evicted. ReplicaSets, StatefulSets, ReplicationControllers and Jobs
+// are supported, pods of Deployments are owned by their ReplicaSets.
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	UID       types.UID
+	// Replicas is the number of pods the workload desires to be running
+	Replicas int32
+	// ReadyReplicas is the number of pods of the workload that are ready
+	ReadyReplicas int32
+}
+
+// Key returns the kind/namespace/name key of the workload.
+func (w *Workload) Key() string {
+	return w.Kind + "/" + w.Namespace + "/" + w.Name
+}
+
+// IsReady checks if all the desired pods of the workload are ready.
+func (w *Workload) IsReady() bool {
+	return w.ReadyReplicas >= w.Replicas
+}
+
+// NewWorkload converts a ReplicaSet, StatefulSet, ReplicationController or Job
+// to Workload, nil is returned for other objects.
+func NewWorkload(obj interface{}) *Workload {
+	switch o := obj.(type) {
+	case *apps_v1.ReplicaSet:
+		return &Workload{
+			Kind:          "ReplicaSet",
+			Namespace:     o.Namespace,
+			Name:          o.Name,
+			UID:           o.UID,
+			Replicas:      replicasOrDefault(o.Spec.Replicas),
+			ReadyReplicas: o.Status.ReadyReplicas,
+		}
+	case *apps_v1.StatefulSet:
+		return &Workload{
+			Kind:          "StatefulSet",
+			Namespace:     o.Namespace,
+			Name:          o.Name,
+			UID:           o.UID,
+			Replicas:      replicasOrDefault(o.Spec.Replicas),
+			ReadyReplicas: o.Status.ReadyReplicas,
+		}
+	case *api_v1.ReplicationController:
+		return &Workload{
+			Kind:          "ReplicationController",
+			Namespace:     o.Namespace,
+			Name:          o.Name,
+			UID:           o.UID,
+			Replicas:      replicasOrDefault(o.Spec.Replicas),
+			ReadyReplicas: o.Status.ReadyReplicas,
+		}
+	case *batch_v1.Job:
+		// Job status doesn't count ready pods, active pods are taken as ready.
+		return &Workload{
+			Kind:          "Job",
+			Namespace:     o.Namespace,
+			Name:          o.Name,
+			UID:           o.UID,
+			Replicas:      jobReplicas(o),
+			ReadyReplicas: o.Status.Active,
+		}
+	}
+	return nil
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// jobReplicas returns the number of pods the job desires to be running now.
+func jobReplicas(job *batch_v1.Job) int32 {
+	for _, cond := range job.Status.Conditions {
+		if (cond.Type == batch_v1.JobComplete || cond.Type == batch_v1.JobFailed) &&
+			cond.Status == api_v1.ConditionTrue {
+			return 0
+		}
+	}
+	replicas := replicasOrDefault(job.Spec.Parallelism)
+	if job.Spec.Completions != nil {
+		if remaining := *job.Spec.Completions - job.Status.Succeeded; remaining < replicas {
+			replicas = remaining
+		}
+	}
+	if replicas < 0 {
+		return 0
+	}
+	return replicas
+}
+
+// workloadIndexer returns the indexer of the workload kind, nil if the kind is not supported.
+func (p *Predictor) workloadIndexer(kind string) cache.Indexer {
+	switch kind {
+	case "ReplicaSet":
+		return p.indexers.RSIndexer
+	case "StatefulSet":
+		return p.indexers.SSIndexer
+	case "ReplicationController":
+		return p.indexers.RCIndexer
+	case "Job":
+		// Job pods are evicted only when spec.rules.evictJobPods is enabled,
+		// their progress is lost after being evicted.
+		if p.conf.Rules.EvictJobPods {
+			return p.indexers.JobIndexer
+		}
+	}
+	return nil
+}
+
+// GetWorkloadByKey returns the workload with the kind/namespace/name key, nil if it doesn't exist.
+func (p *Predictor) GetWorkloadByKey(key string) *Workload {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	indexer := p.workloadIndexer(parts[0])
+	if indexer == nil {
+		return nil
+	}
+	obj, exists, err := indexer.GetByKey(parts[1])
+	if err != nil {
+		logger.Error(err, "Get workload failed", "workload", key)
+		return nil
+	}
+	if !exists {
+		return nil
+	}
+	return NewWorkload(obj)
+}
+
+// getPodWorkload returns the workload controlling the pod, nil if there is no supported one.
+func (p *Predictor) getPodWorkload(pod *api_v1.Pod) *Workload {
+	controllerRef := v1.GetControllerOf(pod)
+	if controllerRef == nil {
+		return nil
+	}
+	w := p.GetWorkloadByKey(controllerRef.Kind + "/" + pod.Namespace + "/" + controllerRef.Name)
+	// The workload with the same name may have been recreated.
+	if w == nil || w.UID != controllerRef.UID {
+		return nil
+	}
+	return w
+}
+
+// GetPodWorkloadKey returns the key of the workload controlling the pod, "" if there is no supported one.
+func (p *Predictor) GetPodWorkloadKey(pod *api_v1.Pod) string {
+	w := p.getPodWorkload(pod)
+	if w != nil {
+		return w.Key()
+	}
+	return ""
+}
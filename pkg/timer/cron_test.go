@@ -0,0 +1,95 @@
+package timer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestCronNext(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		spec, from, next string
+	}{
+		{"0 22 * * *", "2019-03-01T10:00:00Z", "2019-03-01T22:00:00Z"},
+		{"0 22 * * *", "2019-03-01T22:00:00Z", "2019-03-02T22:00:00Z"},
+		{"30 * * * *", "2019-03-01T10:45:10Z", "2019-03-01T11:30:00Z"},
+		{"*/15 9-17 * * *", "2019-03-01T17:50:00Z", "2019-03-02T09:00:00Z"},
+		{"0 22 * * MON-FRI", "2019-03-01T23:00:00Z", "2019-03-04T22:00:00Z"},
+		{"0 0 * * 7", "2019-03-01T00:00:00Z", "2019-03-03T00:00:00Z"},
+		{"0 0 29 2 *", "2019-03-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"0 0 1,15 * 1", "2019-03-05T00:00:00Z", "2019-03-11T00:00:00Z"},
+		{"0 0 1 jan,jul *", "2019-03-01T00:00:00Z", "2019-07-01T00:00:00Z"},
+		{"5/20 0 * * *", "2019-03-01T00:30:00Z", "2019-03-01T00:45:00Z"},
+		{"@weekly", "2019-03-01T00:00:00Z", "2019-03-03T00:00:00Z"},
+		{"0 0 30 2 *", "2019-03-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCron(test.spec, time.UTC)
+		if !assert.NoError(t, err, test.spec) {
+			continue
+		}
+		next := schedule.Next(mustParseTime(t, test.from))
+		assert.Equal(t, mustParseTime(t, test.next).UTC(), next.UTC(), test.spec)
+	}
+}
+
+func TestCronNextTimezone(t *testing.T) {
+	t.Parallel()
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	schedule, _ := ParseCron("0 22 * * *", shanghai)
+	next := schedule.Next(mustParseTime(t, "2019-03-01T10:00:00Z"))
+	assert.Equal(t, mustParseTime(t, "2019-03-01T14:00:00Z").UTC(), next.UTC())
+	assert.Equal(t, shanghai, next.Location())
+}
+
+func TestCronNextDaylightSaving(t *testing.T) {
+	t.Parallel()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2:30 doesn't exist on 2019-03-10, 1:30 happens twice on 2019-11-03.
+	schedule, _ := ParseCron("30 2 * * *", newYork)
+	next := schedule.Next(mustParseTime(t, "2019-03-10T05:00:00Z"))
+	assert.Equal(t, mustParseTime(t, "2019-03-11T06:30:00Z").UTC(), next.UTC())
+
+	schedule, _ = ParseCron("30 1 * * *", newYork)
+	first := schedule.Next(mustParseTime(t, "2019-11-03T04:00:00Z"))
+	assert.Equal(t, mustParseTime(t, "2019-11-03T05:30:00Z").UTC(), first.UTC())
+	second := schedule.Next(first)
+	assert.Equal(t, mustParseTime(t, "2019-11-03T06:30:00Z").UTC(), second.UTC())
+	assert.True(t, schedule.Next(second).After(second))
+}
+
+func TestParseCronErrors(t *testing.T) {
+	t.Parallel()
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@sometimes",
+	} {
+		_, err := ParseCron(spec, time.UTC)
+		assert.Error(t, err, spec)
+	}
+}
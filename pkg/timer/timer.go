@@ -7,70 +7,248 @@ import (
 	"time"
 
 	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/lentil1016/descheduler/pkg/logger"
 )
 
+// window allows descheduling for duration since each time the schedule fires.
+type window struct {
+	schedule *CronSchedule
+	duration time.Duration
+}
+
+// exclusion forbids descheduling from from until to, even inside windows.
+type exclusion struct {
+	from, to time.Time
+}
+
 var outOfTime bool
 var outOfTimeMutex sync.Mutex
-var duration time.Duration
 var pushEvent func()
 
+// mode, windows and exclusions are swapped by ReloadTimer while runTimer reads them.
+var mode string
+var windows []window
+var exclusions []exclusion
+var configMutex sync.Mutex
+
+// reloadCh wakes runTimer up after the config has been reloaded.
+var reloadCh = make(chan struct{}, 1)
+
 func InitTimer(pushEventHandle func()) error {
 	// Set the function which will be called when timer starts.
 	pushEvent = pushEventHandle
 
-	// Disabled trigger first.
+	// Disabled trigger first in time mode.
 	// Is ok to Set `outOfTime` without Lock here because worker are not running yet.
 	// There is no race condition during this method.
-	outOfTime = true
 	conf := config.GetConfig()
-	if conf.Triggers.Mode == "time" {
-		var err error
-		duration, err = time.ParseDuration(conf.Triggers.Time.For)
+	// In event mode, descheduler is triggered not by timer but by event.
+	// This will allow event to be triggered.
+	outOfTime = conf.Triggers.Mode != "event"
+	return applyConfig(conf)
+}
+
+// ValidateConfig checks if the timer can work with the trigger mode and time of the config.
+func ValidateConfig(conf config.ConfigSpec) error {
+	_, _, err := parseConfig(conf)
+	return err
+}
+
+// ReloadTimer swaps the trigger mode and time of the config in, the windows
+// opening or closing with the new config take effect right away.
+func ReloadTimer(conf config.ConfigSpec) error {
+	if err := applyConfig(conf); err != nil {
+		return err
+	}
+	select {
+	case reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func applyConfig(conf config.ConfigSpec) error {
+	parsedWindows, parsedExclusions, err := parseConfig(conf)
+	if err != nil {
+		return err
+	}
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	mode = conf.Triggers.Mode
+	windows = parsedWindows
+	exclusions = parsedExclusions
+	return nil
+}
+
+func parseConfig(conf config.ConfigSpec) ([]window, []exclusion, error) {
+	if conf.Triggers.Mode == "event" {
+		return nil, nil, nil
+	} else if conf.Triggers.Mode != "time" {
+		// Unexpected value check
+		return nil, nil, errors.New("Please check config file. Can't recognize spec.triggers.mode with value " + conf.Triggers.Mode + ", either set it to [event] or [time]")
+	}
+	parsedWindows, err := parseWindows(conf.Triggers.Time)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedExclusions, err := parseExclusions(conf.Triggers.Time)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parsedWindows, parsedExclusions, nil
+}
+
+// parseWindows parses spec.triggers.time.windows, falling back to a daily
+// window starting at spec.triggers.time.from in the local timezone.
+func parseWindows(conf config.ConfigTime) ([]window, error) {
+	configWindows := conf.Windows
+	if len(configWindows) == 0 {
+		hour, min, _ := conf.From.Clock()
+		configWindows = []config.ConfigWindow{{
+			Schedule: fmt.Sprintf("%d %d * * *", min, hour),
+			For:      conf.For,
+		}}
+	}
+	var parsed []window
+	for i, w := range configWindows {
+		location, err := loadLocation(w.Timezone)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("Please check config file. Can't load timezone %q of spec.triggers.time.windows[%v]: %v", w.Timezone, i, err)
 		}
-	} else if conf.Triggers.Mode == "event" {
-		// In event mode, descheduler is triggered not by timer but by event.
-		// This will allow event to be triggered.
-		outOfTime = false
-	} else {
-		// Unexpected value check
-		return errors.New("Please check config file. Can't recognize spec.triggers.mode with value " + conf.Triggers.Mode + ", either set it to [event] or [time]")
+		schedule, err := ParseCron(w.Schedule, location)
+		if err != nil {
+			return nil, fmt.Errorf("Please check config file. spec.triggers.time.windows[%v]: %v", i, err)
+		}
+		duration, err := time.ParseDuration(w.For)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("Please check config file. Can't parse spec.triggers.time.windows[%v].for with value %q as a positive duration", i, w.For)
+		}
+		parsed = append(parsed, window{schedule: schedule, duration: duration})
 	}
-	return nil
+	return parsed, nil
 }
 
-func RunTimer() {
-	conf := config.GetConfig()
-	if conf.Triggers.Mode == "time" {
-		hour, min, _ := conf.Triggers.Time.From.Clock()
-		go runTimerAt(hour, min)
+// parseExclusions parses spec.triggers.time.exclusions. Both ends are either
+// RFC3339 times or dates in the timezone of the exclusion, a date as the end
+// of the exclusion includes the whole day.
+func parseExclusions(conf config.ConfigTime) ([]exclusion, error) {
+	var parsed []exclusion
+	for i, e := range conf.Exclusions {
+		location, err := loadLocation(e.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Please check config file. Can't load timezone %q of spec.triggers.time.exclusions[%v]: %v", e.Timezone, i, err)
+		}
+		from, _, err := parseExclusionTime(e.From, location)
+		if err != nil {
+			return nil, fmt.Errorf("Please check config file. spec.triggers.time.exclusions[%v].from: %v", i, err)
+		}
+		to, isDate, err := parseExclusionTime(e.To, location)
+		if err != nil {
+			return nil, fmt.Errorf("Please check config file. spec.triggers.time.exclusions[%v].to: %v", i, err)
+		}
+		if isDate {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !to.After(from) {
+			return nil, fmt.Errorf("Please check config file. spec.triggers.time.exclusions[%v] ends before it starts", i)
+		}
+		parsed = append(parsed, exclusion{from: from, to: to})
+	}
+	return parsed, nil
+}
+
+// loadLocation loads the IANA timezone, "" is the local timezone.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+func parseExclusionTime(value string, location *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("can't parse %q as a RFC3339 time or a 2006-01-02 date", value)
+	}
+	return t, true, nil
+}
+
+// timeState tells if descheduling is allowed at now, and the next time that may change.
+// The zero time is returned as next if no window will ever start again.
+func timeState(windows []window, exclusions []exclusion, now time.Time) (bool, time.Time) {
+	inWindow, excluded := false, false
+	var next time.Time
+	earlier := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for _, w := range windows {
+		// The earliest start of the window that still covers now.
+		if start := w.schedule.Next(now.Add(-w.duration)); !start.IsZero() && !start.After(now) {
+			inWindow = true
+			earlier(start.Add(w.duration))
+		}
+		earlier(w.schedule.Next(now))
+	}
+	for _, e := range exclusions {
+		if !now.Before(e.from) && now.Before(e.to) {
+			excluded = true
+		}
+		if e.from.After(now) {
+			earlier(e.from)
+		}
+		if e.to.After(now) {
+			earlier(e.to)
+		}
 	}
+	return inWindow && !excluded, next
+}
 
+func RunTimer() {
+	go runTimer()
 }
 
-func runTimerAt(hour int, min int) {
+// runTimer sleeps until the next time the windows or exclusions may open or
+// close descheduling, or until the config is reloaded. It pushes an event
+// every time a window opens. Descheduling is always allowed in event mode.
+func runTimer() {
+	open := false
 	for {
-		// If now is the time that user configured in spec.triggers.time.from, start a timer.
-		if curHour, curMin, _ := time.Now().Clock(); curHour == hour && curMin == min {
-			timer := time.NewTimer(duration)
-			fmt.Printf("Timer started at %v:%v, last for %v\n", hour, min, duration.String())
-			{
-				outOfTimeMutex.Lock()
-				outOfTime = false
-				outOfTimeMutex.Unlock()
-			}
-			pushEvent()
-			// wait util timer stopped
-			<-timer.C
-			{
-				outOfTimeMutex.Lock()
-				outOfTime = true
-				outOfTimeMutex.Unlock()
+		configMutex.Lock()
+		currentMode, currentWindows, currentExclusions := mode, windows, exclusions
+		configMutex.Unlock()
+
+		now := time.Now()
+		isOpen, next := true, time.Time{}
+		if currentMode == "time" {
+			isOpen, next = timeState(currentWindows, currentExclusions, now)
+		}
+		if isOpen != open {
+			outOfTimeMutex.Lock()
+			outOfTime = !isOpen
+			outOfTimeMutex.Unlock()
+			// Nothing to push in event mode, descheduler is triggered by events.
+			if isOpen && currentMode == "time" {
+				logger.Info("Timer started", "next", next.Format(time.RFC3339))
+				pushEvent()
+			} else if !isOpen {
+				logger.Info("Timer stopped", "next", next.Format(time.RFC3339))
 			}
-			fmt.Println("Timer stopped")
-		} else {
-			time.Sleep(20 * time.Second)
+			open = isOpen
+		}
+		var wake <-chan time.Time
+		if !next.IsZero() {
+			wake = time.After(next.Sub(now))
+		} else if currentMode == "time" {
+			logger.Info("Timer finished, no window will start again")
+		}
+		select {
+		case <-wake:
+		case <-reloadCh:
 		}
 	}
 }
@@ -81,5 +259,7 @@ func PushTimerEventAfter(duration time.Duration) {
 }
 
 func IsOutOfTime() bool {
+	outOfTimeMutex.Lock()
+	defer outOfTimeMutex.Unlock()
 	return outOfTime
 }
@@ -0,0 +1,95 @@
+package timer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lentil1016/descheduler/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeState(t *testing.T) {
+	t.Parallel()
+	windows, err := parseWindows(config.ConfigTime{Windows: []config.ConfigWindow{
+		{Schedule: "0 22 * * MON-FRI", For: "2h", Timezone: "UTC"},
+		{Schedule: "0 12 * * *", For: "30m", Timezone: "UTC"},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	exclusions, err := parseExclusions(config.ConfigTime{Exclusions: []config.ConfigExclusion{
+		{From: "2019-03-06", To: "2019-03-07", Timezone: "UTC"},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	tests := []struct {
+		now  string
+		open bool
+		next string
+	}{
+		// Friday
+		{"2019-03-01T11:00:00Z", false, "2019-03-01T12:00:00Z"},
+		{"2019-03-01T12:00:00Z", true, "2019-03-01T12:30:00Z"},
+		{"2019-03-01T12:30:00Z", false, "2019-03-01T22:00:00Z"},
+		{"2019-03-01T23:59:00Z", true, "2019-03-02T00:00:00Z"},
+		// Saturday night is not in the weekday window.
+		{"2019-03-02T22:30:00Z", false, "2019-03-03T12:00:00Z"},
+		// Wednesday and Thursday are excluded.
+		{"2019-03-05T23:00:00Z", true, "2019-03-06T00:00:00Z"},
+		{"2019-03-06T12:10:00Z", false, "2019-03-06T12:30:00Z"},
+		{"2019-03-07T22:30:00Z", false, "2019-03-08T00:00:00Z"},
+		{"2019-03-08T00:00:00Z", false, "2019-03-08T12:00:00Z"},
+	}
+	for _, test := range tests {
+		open, next := timeState(windows, exclusions, mustParseTime(t, test.now))
+		assert.Equal(t, test.open, open, test.now)
+		assert.Equal(t, mustParseTime(t, test.next).UTC(), next.UTC(), test.now)
+	}
+}
+
+func TestTimeStateLegacyWindow(t *testing.T) {
+	t.Parallel()
+	from, _ := time.Parse(time.Kitchen, "10:00PM")
+	windows, err := parseWindows(config.ConfigTime{From: config.ClockTime{Time: from}, For: "1h"})
+	if !assert.NoError(t, err) || !assert.Len(t, windows, 1) {
+		return
+	}
+	now := time.Date(2019, 3, 1, 22, 30, 0, 0, time.Local)
+	open, next := timeState(windows, nil, now)
+	assert.True(t, open)
+	assert.Equal(t, time.Date(2019, 3, 1, 23, 0, 0, 0, time.Local).Unix(), next.Unix())
+}
+
+func TestParseTimeConfigErrors(t *testing.T) {
+	t.Parallel()
+	for _, w := range []config.ConfigWindow{
+		{Schedule: "0 22 * *", For: "1h"},
+		{Schedule: "0 22 * * *", For: "forever"},
+		{Schedule: "0 22 * * *", For: "-1h"},
+		{Schedule: "0 22 * * *", For: "1h", Timezone: "Mars/Olympus"},
+	} {
+		_, err := parseWindows(config.ConfigTime{Windows: []config.ConfigWindow{w}})
+		assert.Error(t, err, w.Schedule)
+	}
+	for _, e := range []config.ConfigExclusion{
+		{From: "2019-03-06", To: "tomorrow"},
+		{From: "2019-03-06", To: "2019-03-05"},
+		{From: "2019-03-06T10:00:00Z", To: "2019-03-06T10:00:00Z"},
+	} {
+		_, err := parseExclusions(config.ConfigTime{Exclusions: []config.ConfigExclusion{e}})
+		assert.Error(t, err, e.From+" "+e.To)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	conf := config.DefaultConfig()
+	assert.NoError(t, ValidateConfig(conf))
+	conf.Triggers.Mode = "sometimes"
+	assert.Error(t, ValidateConfig(conf))
+	conf.Triggers.Mode = "time"
+	assert.NoError(t, ValidateConfig(conf))
+	conf.Triggers.Time.Windows = []config.ConfigWindow{{Schedule: "0 22 * * *", For: "never"}}
+	assert.Error(t, ValidateConfig(conf))
+}